@@ -0,0 +1,204 @@
+package wowlua
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotInteger indicates a number has no exact integer representation
+	ErrNotInteger = errors.New("Number is not an integer")
+)
+
+// The As accessors return a node's value or a *PathError explaining why they
+// couldn't. A nil node gives ErrNotFound so lookups can be chained:
+//
+//	title, err := table.GetByString("title").AsString()
+//
+// The Table variants look the value up by path first and report the full path
+// in any error. The Or variants return a default instead of an error.
+
+func (n *Node) checkType(expected NodeType) error {
+	if n == nil {
+		return &PathError{Err: ErrNotFound}
+	}
+	if n.nType != expected {
+		return &PathError{Err: ErrWrongType, Expected: expected, Actual: n.nType}
+	}
+	return nil
+}
+
+// AsString returns the node's string value. Identifiers count as strings.
+func (n *Node) AsString() (string, error) {
+	if n != nil && n.nType == NodeTypeIdentifier {
+		return n.GetString(), nil
+	}
+	if err := n.checkType(NodeTypeString); err != nil {
+		return "", err
+	}
+	return n.GetString(), nil
+}
+
+// AsInt returns the node's numeric value as an integer. Floats are accepted if
+// they have an exact integer value; otherwise the error wraps ErrNotInteger.
+func (n *Node) AsInt() (int64, error) {
+	if err := n.checkType(NodeTypeNumber); err != nil {
+		return 0, err
+	}
+	i, ok := n.GetNumber().Int64()
+	if !ok {
+		return 0, &PathError{Err: ErrNotInteger}
+	}
+	return i, nil
+}
+
+// AsFloat returns the node's numeric value as a float.
+func (n *Node) AsFloat() (float64, error) {
+	if err := n.checkType(NodeTypeNumber); err != nil {
+		return NaN, err
+	}
+	return n.GetFloat64(), nil
+}
+
+// AsBool returns the node's bool value.
+func (n *Node) AsBool() (bool, error) {
+	if err := n.checkType(NodeTypeBool); err != nil {
+		return false, err
+	}
+	return n.GetBool(), nil
+}
+
+// AsTable returns the node's table. The error for a node of another type wraps
+// ErrNotTable.
+func (n *Node) AsTable() (*Table, error) {
+	if n != nil && n.nType != NodeTypeTable {
+		return nil, notTableError(nil, n)
+	}
+	if err := n.checkType(NodeTypeTable); err != nil {
+		return nil, err
+	}
+	return n.GetTable(), nil
+}
+
+// AsStringOr returns the node's string value or def if it doesn't have one.
+func (n *Node) AsStringOr(def string) string {
+	if v, err := n.AsString(); err == nil {
+		return v
+	}
+	return def
+}
+
+// AsIntOr returns the node's integer value or def if it doesn't have one.
+func (n *Node) AsIntOr(def int64) int64 {
+	if v, err := n.AsInt(); err == nil {
+		return v
+	}
+	return def
+}
+
+// AsFloatOr returns the node's float value or def if it doesn't have one.
+func (n *Node) AsFloatOr(def float64) float64 {
+	if v, err := n.AsFloat(); err == nil {
+		return v
+	}
+	return def
+}
+
+// AsBoolOr returns the node's bool value or def if it doesn't have one.
+func (n *Node) AsBoolOr(def bool) bool {
+	if v, err := n.AsBool(); err == nil {
+		return v
+	}
+	return def
+}
+
+// lookup finds the node at the path for the Table accessors. Errors from the
+// node accessor are given the path by WithPath.
+func (t *Table) lookup(path []*Node) (*Node, error) {
+	_, n, err := t.getPath(path)
+	return n, err
+}
+
+// AsString returns the string at the path.
+func (t *Table) AsString(path ...*Node) (string, error) {
+	n, err := t.lookup(path)
+	if err != nil {
+		return "", err
+	}
+	v, err := n.AsString()
+	return v, WithPath(err, path...)
+}
+
+// AsInt returns the integer at the path.
+func (t *Table) AsInt(path ...*Node) (int64, error) {
+	n, err := t.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := n.AsInt()
+	return v, WithPath(err, path...)
+}
+
+// AsFloat returns the number at the path as a float.
+func (t *Table) AsFloat(path ...*Node) (float64, error) {
+	n, err := t.lookup(path)
+	if err != nil {
+		return NaN, err
+	}
+	v, err := n.AsFloat()
+	return v, WithPath(err, path...)
+}
+
+// AsBool returns the bool at the path.
+func (t *Table) AsBool(path ...*Node) (bool, error) {
+	n, err := t.lookup(path)
+	if err != nil {
+		return false, err
+	}
+	v, err := n.AsBool()
+	return v, WithPath(err, path...)
+}
+
+// AsTable returns the table at the path. An empty path returns t itself.
+func (t *Table) AsTable(path ...*Node) (*Table, error) {
+	if len(path) == 0 {
+		return t, nil
+	}
+	n, err := t.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := n.AsTable()
+	return v, WithPath(err, path...)
+}
+
+// AsStringOr returns the string at the path or def if there isn't one.
+func (t *Table) AsStringOr(def string, path ...*Node) string {
+	if v, err := t.AsString(path...); err == nil {
+		return v
+	}
+	return def
+}
+
+// AsIntOr returns the integer at the path or def if there isn't one.
+func (t *Table) AsIntOr(def int64, path ...*Node) int64 {
+	if v, err := t.AsInt(path...); err == nil {
+		return v
+	}
+	return def
+}
+
+// AsFloatOr returns the number at the path or def if there isn't one.
+func (t *Table) AsFloatOr(def float64, path ...*Node) float64 {
+	if v, err := t.AsFloat(path...); err == nil {
+		return v
+	}
+	return def
+}
+
+// AsBoolOr returns the bool at the path or def if there isn't one.
+func (t *Table) AsBoolOr(def bool, path ...*Node) bool {
+	if v, err := t.AsBool(path...); err == nil {
+		return v
+	}
+	return def
+}
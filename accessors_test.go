@@ -0,0 +1,66 @@
+package wowlua
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccessors(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	event := NewPath("HarbingerTools_Events", "Guilds", "Moon Guard", "Harbingers of Discord", 1)
+
+	if title, err := tab.AsString(append(event, NewPath("title")...)...); err != nil || title != "Hell's Gate RP-PvP Event" {
+		t.Errorf("Expected title, got %q, %v", title, err)
+	}
+	if hour, err := tab.AsInt(append(event, NewPath("hour")...)...); err != nil || hour != 19 {
+		t.Errorf("Expected hour 19, got %v, %v", hour, err)
+	}
+
+	_, err = tab.AsInt(append(event, NewPath("title")...)...)
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) || !errors.Is(err, ErrWrongType) {
+		t.Fatalf("Expected a *PathError wrapping ErrWrongType, got %v", err)
+	}
+	if pathErr.Expected != NodeTypeNumber || pathErr.Actual != NodeTypeString {
+		t.Errorf("Expected number/string mismatch, got %v/%v", pathErr.Expected, pathErr.Actual)
+	}
+	expected_msg := `HarbingerTools_Events/Guilds/"Moon Guard"/"Harbingers of Discord"[1]/title: Node is wrong type: expected number, got string`
+	if err.Error() != expected_msg {
+		t.Errorf("Expected error %q, got %q", expected_msg, err)
+	}
+
+	if _, err := tab.AsBool(append(event, NewPath("missing")...)...); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if v := tab.AsIntOr(-1, append(event, NewPath("missing")...)...); v != -1 {
+		t.Errorf("Expected default -1, got %v", v)
+	}
+	if v := tab.GetByString("missing").AsStringOr("none"); v != "none" {
+		t.Errorf("Expected default %q, got %q", "none", v)
+	}
+	if _, err := NodeOf(FloatNumber(1.5)).AsInt(); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Expected ErrNotInteger, got %v", err)
+	}
+}
+
+func TestGetErrorsAreSentinels(t *testing.T) {
+	tab := Map("name", "Volne", "level", 90)
+	if _, err := tab.GetStringByString("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := tab.GetStringByString("level"); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	if _, err := tab.GetFloat64ByString("name"); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	if _, _, err := tab.GetStringPath("name", "first"); err != ErrNotTable {
+		t.Errorf("Expected ErrNotTable, got %v", err)
+	}
+	if _, _, err := tab.GetStringPath("missing", "first"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package wowlua
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syntheticCalendar returns SavedVariables text shaped like the calendar
+// sample data with the given number of events.
+func syntheticCalendar(events int) string {
+	var b strings.Builder
+	b.WriteString("HarbingerTools_Events = {\n\t[\"Guilds\"] = {\n\t\t[\"Moon Guard\"] = {\n")
+	for i := 0; i < events; i++ {
+		fmt.Fprintf(&b, `			{
+				["nowYear"] = 2014,
+				["title"] = "Event %d",
+				["day"] = %d,
+				["inviteStatus"] = 8,
+				["modStatus"] = "",
+				["eventType"] = %d,
+				["month"] = -1,
+				["difficulty"] = 0,
+				["sequenceType"] = "",
+				["nowMonth"] = 11,
+				["calendarType"] = "GUILD_EVENT",
+				["inviteType"] = 2,
+				["minute"] = %d,
+				["hour"] = %d,
+				["invitedBy"] = "Player%d",
+			}, -- [%d]
+`, i, i%28+1, i%6, i%60, i%24, i%100, i+1)
+	}
+	b.WriteString("\t\t},\n\t},\n}\n")
+	return b.String()
+}
+
+func benchmarkParseRetained(b *testing.B, interner func() *Interner) {
+	data := syntheticCalendar(20000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	var retained uint64
+	var before, after runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		b.StartTimer()
+
+		tab, err := ParseLuaInterned(data, interner())
+		if err != nil {
+			b.Fatalf("Unexpected error parsing data: %q", err)
+		}
+
+		b.StopTimer()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		retained += after.HeapAlloc - before.HeapAlloc
+		runtime.KeepAlive(tab)
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+}
+
+func BenchmarkParseInterned(b *testing.B) {
+	benchmarkParseRetained(b, NewInterner)
+}
+
+func BenchmarkParseSharedInterner(b *testing.B) {
+	in := NewInterner()
+	benchmarkParseRetained(b, func() *Interner { return in })
+}
+
+func BenchmarkParseNotInterned(b *testing.B) {
+	benchmarkParseRetained(b, func() *Interner { return nil })
+}
+
+var (
+	largeCalendarOnce sync.Once
+	largeCalendar     string
+)
+
+// largeCalendarData returns about 100 MB of synthetic calendar data, built
+// the first time it's needed.
+func largeCalendarData() string {
+	largeCalendarOnce.Do(func() {
+		largeCalendar = syntheticCalendar(230000)
+	})
+	return largeCalendar
+}
+
+func BenchmarkTokenize100MB(b *testing.B) {
+	data := largeCalendarData()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		count := 0
+		err := NewTokenizer(data, func(*Token) error {
+			count++
+			return nil
+		}).Tokenize()
+		if err != nil {
+			b.Fatalf("Unexpected error tokenizing: %v", err)
+		}
+	}
+}
+
+func BenchmarkTokenizeNext100MB(b *testing.B) {
+	data := largeCalendarData()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tok := NewTokenizer(data, nil)
+		for {
+			if _, err := tok.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
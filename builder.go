@@ -0,0 +1,200 @@
+package wowlua
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Str returns a String value.
+func Str(s string) Value {
+	return String(s)
+}
+
+// Num returns a float Number value.
+func Num(f float64) Value {
+	return FloatNumber(f)
+}
+
+// Int returns an integer Number value.
+func Int(i int64) Value {
+	return IntNumber(i)
+}
+
+// Bool values need no constructor; Bool(true) is already a Value.
+
+// Seq returns a table with the provided values as its sequence, keyed from 1.
+// Values may be anything accepted by Map. Seq panics if given a type it
+// can't convert.
+func Seq(values ...interface{}) *Table {
+	t := NewTable()
+	for i, v := range values {
+		t.Set(intKey(i+1), NodeOf(mustValue(v)))
+	}
+	return t
+}
+
+// Map returns a table built from alternating keys and values. Keys and values
+// may be Values, Nodes, or Go strings, integers, floats and bools of any size
+// or named type, which are converted to the equivalent Value. Map panics if
+// given an odd number of arguments, a type it can't convert, an unsigned
+// integer too large for an int64, or a nil or NaN key.
+func Map(kvs ...interface{}) *Table {
+	if len(kvs)%2 != 0 {
+		panic("wowlua: Map called with an odd number of arguments")
+	}
+	t := NewTable()
+	for i := 0; i < len(kvs); i += 2 {
+		t.Set(NodeOf(mustKey(kvs[i])), NodeOf(mustValue(kvs[i+1])))
+	}
+	return t
+}
+
+// A Builder constructs a table by setting values at nested paths, creating
+// intermediate tables as needed. The first error encountered, including a
+// value Map would panic on, is reported by Build and later calls do nothing.
+type Builder struct {
+	table *Table
+	err   error
+}
+
+// NewBuilder creates a Builder for a new, empty table.
+func NewBuilder() *Builder {
+	return &Builder{table: NewTable()}
+}
+
+// Set stores the value at the path, creating any missing intermediate
+// tables. The value and path elements may be anything accepted by Map.
+func (b *Builder) Set(value interface{}, path ...interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+	v, err := toValue(value)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	p, err := toPath(path)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.err = b.table.SetPath(NodeOf(v), p...)
+	return b
+}
+
+// Append adds the values to the end of the sequence at the path, creating the
+// table and any intermediate tables if they're missing.
+func (b *Builder) Append(path []interface{}, values ...interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+	p, err := toPath(path)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	nodes := make([]*Node, len(values))
+	for i, v := range values {
+		value, err := toValue(v)
+		if err != nil {
+			b.err = err
+			return b
+		}
+		nodes[i] = NodeOf(value)
+	}
+	t, err := b.table.EnsureTable(p...)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	for _, n := range nodes {
+		t.AddIndexed(n)
+	}
+	return b
+}
+
+// Build returns the constructed table and the first error encountered.
+func (b *Builder) Build() (*Table, error) {
+	return b.table, b.err
+}
+
+func toPath(elems []interface{}) (Path, error) {
+	p := make(Path, len(elems))
+	for i, e := range elems {
+		k, err := toKey(e)
+		if err != nil {
+			return nil, err
+		}
+		p[i] = NodeOf(k)
+	}
+	return p, nil
+}
+
+// mustValue is toValue for Map and Seq, which panic on types they can't
+// convert.
+func mustValue(v interface{}) Value {
+	value, err := toValue(v)
+	if err != nil {
+		panic("wowlua: " + err.Error())
+	}
+	return value
+}
+
+// mustKey is toKey for Map and NewPath, which panic on keys they can't
+// convert.
+func mustKey(v interface{}) Value {
+	key, err := toKey(v)
+	if err != nil {
+		panic("wowlua: " + err.Error())
+	}
+	return key
+}
+
+// toKey converts a key as toValue does, rejecting nil and NaN, which can't
+// be table keys.
+func toKey(v interface{}) (Value, error) {
+	key, err := toValue(v)
+	if err != nil {
+		return nil, err
+	}
+	n := NodeOf(key)
+	switch {
+	case n.IsNil():
+		return nil, errors.New("table key is nil")
+	case n.GetType() == NodeTypeNumber && math.IsNaN(n.GetFloat64()):
+		return nil, errors.New("table key is NaN")
+	}
+	return key, nil
+}
+
+// toValue converts the Go types accepted by Map into a Value.
+func toValue(v interface{}) (Value, error) {
+	switch tv := v.(type) {
+	case nil:
+		return Nil{}, nil
+	case Value:
+		return tv, nil
+	case *Node:
+		return tv.Value(), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return String(rv.String()), nil
+	case reflect.Bool:
+		return Bool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntNumber(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %d", ErrOverflow, u)
+		}
+		return IntNumber(int64(u)), nil
+	case reflect.Float32, reflect.Float64:
+		return FloatNumber(rv.Float()), nil
+	}
+	return nil, fmt.Errorf("can't convert %T to a Value", v)
+}
@@ -0,0 +1,77 @@
+package wowlua
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBuilderMatchesParsed(t *testing.T) {
+	parsed, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+
+	promote := func(player1, player2 string) *Table {
+		return Map("type", "promote", "player1", player1, "player2", player2,
+			"hour", 1, "year", 0, "month", 0, "day", 17, "rank", "Harbinger",
+			"now", "Sat Nov 01 07:14:56 2014 UTC")
+	}
+	built, err := NewBuilder().
+		Set(Seq(
+			promote("Jasbyn", "Irut"),
+			promote("Volne", "Briarflower"),
+			promote("Volne", "Dalega"),
+			promote("Volne", "Kruto"),
+		), "HarbingerTools_GuildLog", "Moon Guard", "Harbingers of Discord").
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error building: %v", err)
+	}
+	expected := parsed.GetByString("HarbingerTools_GuildLog")
+	if !built.GetByString("HarbingerTools_GuildLog").Equals(expected) {
+		t.Errorf("Expected built table to equal parsed table")
+	}
+}
+
+func TestBuilderConflict(t *testing.T) {
+	_, err := NewBuilder().
+		Set("Volne", "Characters", "Moon Guard").
+		Append([]interface{}{"Characters", "Moon Guard", "Volne"}, Str("event")).
+		Build()
+	if !errors.Is(err, ErrNotTable) {
+		t.Errorf("Expected ErrNotTable, got %v", err)
+	}
+}
+
+func TestBuilderUnsupportedType(t *testing.T) {
+	_, err := NewBuilder().
+		Set(struct{}{}, "Config", "bad").
+		Set("later", "Config", "name").
+		Build()
+	if err == nil || err.Error() != "can't convert struct {} to a Value" {
+		t.Errorf("Expected an error for an unsupported type, got %v", err)
+	}
+	_, err = NewBuilder().Append([]interface{}{"Log", []int{1}}, "x").Build()
+	if err == nil {
+		t.Errorf("Expected an error for an unsupported path type")
+	}
+}
+
+func TestBuilderIntegerKinds(t *testing.T) {
+	type level uint8
+	tab := Map("a", int8(-1), "b", int16(2), "c", uint(3), "d", uint8(90), "e", uint16(5), "f", uint64(6), "g", level(7))
+	expected := Map("a", -1, "b", 2, "c", 3, "d", 90, "e", 5, "f", 6, "g", 7)
+	if !tab.Equals(expected) {
+		t.Errorf("Expected %v, got %v", expected, tab)
+	}
+	if _, err := NewBuilder().Set(uint64(1<<63), "big").Build(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected ErrOverflow for a uint64 above MaxInt64, got %v", err)
+	}
+	if _, err := NewBuilder().Set(1, "Config", nil).Build(); err == nil {
+		t.Errorf("Expected an error for a nil key")
+	}
+	if _, err := NewBuilder().Set(1, math.NaN()).Build(); err == nil {
+		t.Errorf("Expected an error for a NaN key")
+	}
+}
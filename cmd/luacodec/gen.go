@@ -0,0 +1,607 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const wowluaPath = "github.com/jasonmf/wowlua"
+
+func errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format, args...)
+}
+
+// Kinds of basic type, by name.
+const (
+	basicString = iota
+	basicBool
+	basicInt
+	basicUint
+	basicFloat
+)
+
+var basics = map[string]int{
+	"string":  basicString,
+	"bool":    basicBool,
+	"int":     basicInt,
+	"int8":    basicInt,
+	"int16":   basicInt,
+	"int32":   basicInt,
+	"int64":   basicInt,
+	"rune":    basicInt,
+	"uint":    basicUint,
+	"uint8":   basicUint,
+	"uint16":  basicUint,
+	"uint32":  basicUint,
+	"uint64":  basicUint,
+	"uintptr": basicUint,
+	"byte":    basicUint,
+	"float32": basicFloat,
+	"float64": basicFloat,
+}
+
+type structInfo struct {
+	name   string
+	fields []fieldInfo
+}
+
+type fieldInfo struct {
+	name      string // Go field name
+	key       string // Table key
+	omitEmpty bool
+	typ       ast.Expr
+}
+
+// A codec is the pair of helper functions generated for one field type.
+type codec struct {
+	name string // Suffix of the helper names, such as SliceString
+	typ  ast.Expr
+}
+
+type generator struct {
+	pkg     string
+	structs []*structInfo
+	marked  map[string]bool
+	imports map[string]string   // Import path to name, or "" for the default
+	paths   map[string]string   // Import name to path
+	named   map[string]ast.Expr // Types declared in the package, by name
+	pkgs    map[string]*types.Package
+
+	codecs map[string]*codec // By type text
+	names  map[string]bool   // Codec names in use
+	queue  []*codec          // Codecs still to write
+	needs  map[string]bool   // Standard packages the generated code uses
+	errs   []string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		marked:  make(map[string]bool),
+		imports: make(map[string]string),
+		paths:   make(map[string]string),
+		named:   make(map[string]ast.Expr),
+		pkgs:    make(map[string]*types.Package),
+		codecs:  make(map[string]*codec),
+		names:   make(map[string]bool),
+		needs:   make(map[string]bool),
+	}
+}
+
+// addStruct records a struct to generate methods for. file is the file it's
+// declared in, for resolving the imports its field types use.
+func (g *generator) addStruct(name string, st *ast.StructType, file *ast.File) {
+	s := &structInfo{name: name}
+	g.structs = append(g.structs, s)
+	g.marked[name] = true
+	seen := make(map[string]string) // Field names by key
+	for _, f := range st.Fields.List {
+		tag := ""
+		hasTag := false
+		if f.Tag != nil {
+			unquoted, err := strconv.Unquote(f.Tag.Value)
+			if err == nil {
+				tag, hasTag = reflect.StructTag(unquoted).Lookup("lua")
+			}
+		}
+		if tag == "-" {
+			continue
+		}
+		if len(f.Names) == 0 {
+			g.errs = append(g.errs, fmt.Sprintf("%s: embedded field %s isn't supported", name, types.ExprString(f.Type)))
+			continue
+		}
+		g.addImports(f.Type, file)
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			fi := fieldInfo{name: ident.Name, key: ident.Name, typ: f.Type}
+			if hasTag {
+				parts := strings.Split(tag, ",")
+				if parts[0] != "" {
+					fi.key = parts[0]
+				}
+				for _, opt := range parts[1:] {
+					if opt == "omitempty" {
+						fi.omitEmpty = true
+					}
+				}
+			}
+			if other, ok := seen[fi.key]; ok {
+				g.errs = append(g.errs, fmt.Sprintf("%s: fields %s and %s have the same key %q", name, other, fi.name, fi.key))
+				continue
+			}
+			seen[fi.key] = fi.name
+			s.fields = append(s.fields, fi)
+		}
+	}
+}
+
+// addImports records the imports of file that typ refers to.
+func (g *generator) addImports(typ ast.Expr, file *ast.File) {
+	ast.Inspect(typ, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if path == wowluaPath {
+				continue
+			}
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name == pkg.Name {
+				if spec.Name != nil {
+					g.imports[path] = name
+				} else {
+					g.imports[path] = ""
+				}
+				g.paths[name] = path
+			}
+		}
+		return false
+	})
+}
+
+// generate returns the formatted source of the generated file.
+func (g *generator) generate() ([]byte, error) {
+	var body bytes.Buffer
+	for _, s := range g.structs {
+		g.writeStruct(&body, s)
+	}
+	for len(g.queue) > 0 {
+		c := g.queue[0]
+		g.queue = g.queue[1:]
+		g.writeCodec(&body, c)
+	}
+	if len(g.errs) > 0 {
+		return nil, errorf("%s", strings.Join(g.errs, "; "))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by luacodec; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+	b.WriteString("import (\n")
+	for path := range g.needs {
+		fmt.Fprintf(&b, "%q\n", path)
+	}
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s %q\n", g.imports[path], path)
+	}
+	fmt.Fprintf(&b, "\n%q\n)\n", wowluaPath)
+	b.Write(body.Bytes())
+	return format.Source(b.Bytes())
+}
+
+func (g *generator) writeStruct(b *bytes.Buffer, s *structInfo) {
+	keyVar := func(f fieldInfo) string {
+		return "luaKey" + s.name + f.name
+	}
+	if len(s.fields) > 0 {
+		b.WriteString("var (\n")
+		for _, f := range s.fields {
+			fmt.Fprintf(b, "%s = wowlua.NewNode(wowlua.NodeTypeString, %q)\n", keyVar(f), f.key)
+		}
+		b.WriteString(")\n\n")
+	}
+
+	fmt.Fprintf(b, "// UnmarshalLua implements wowlua.LuaUnmarshaler.\n")
+	fmt.Fprintf(b, "func (v *%s) UnmarshalLua(n *wowlua.Node) error {\n", s.name)
+	b.WriteString("if n.IsNil() {\nreturn nil\n}\n")
+	if len(s.fields) == 0 {
+		b.WriteString("_, err := n.AsTable()\nreturn err\n}\n\n")
+	} else {
+		b.WriteString("t, err := n.AsTable()\nif err != nil {\nreturn err\n}\n")
+		b.WriteString("t.Range(func(k, val *wowlua.Node) bool {\n")
+		b.WriteString("if k.GetType() != wowlua.NodeTypeString {\nreturn true\n}\n")
+		b.WriteString("switch k.GetString() {\n")
+		for _, f := range s.fields {
+			fmt.Fprintf(b, "case %q:\nerr = luaDecode%s(val, &v.%s)\n", f.key, g.codec(f.typ), f.name)
+		}
+		b.WriteString("}\n")
+		b.WriteString("if err != nil {\nerr = wowlua.WithPath(err, k)\nreturn false\n}\nreturn true\n})\n")
+		b.WriteString("return err\n}\n\n")
+	}
+
+	fmt.Fprintf(b, "// MarshalLua implements wowlua.LuaMarshaler.\n")
+	fmt.Fprintf(b, "func (v %s) MarshalLua() (*wowlua.Node, error) {\n", s.name)
+	b.WriteString("t := wowlua.NewTable()\n")
+	if len(s.fields) > 0 {
+		b.WriteString("var (\nn *wowlua.Node\nerr error\n)\n")
+	}
+	for _, f := range s.fields {
+		set := fmt.Sprintf("n, err = luaEncode%s(v.%s)\nif err != nil {\nreturn nil, wowlua.WithPath(err, %s)\n}\nt.Set(%s, n)\n",
+			g.codec(f.typ), f.name, keyVar(f), keyVar(f))
+		if cond := g.nonEmpty(f.typ, "v."+f.name); f.omitEmpty && cond != "" {
+			fmt.Fprintf(b, "if %s {\n%s}\n", cond, set)
+		} else {
+			b.WriteString(set)
+		}
+	}
+	b.WriteString("return wowlua.NodeOf(t), nil\n}\n\n")
+}
+
+// nonEmpty returns a condition that x, of type typ, isn't empty in the sense
+// of omitempty, or "" if it never is. Named types are judged by their
+// underlying type, as reflection does.
+func (g *generator) nonEmpty(typ ast.Expr, x string) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		if g.marked[t.Name] {
+			return ""
+		}
+		if u, ok := g.named[t.Name]; ok {
+			return g.nonEmpty(u, x)
+		}
+		kind, ok := basics[t.Name]
+		switch {
+		case !ok:
+			return ""
+		case kind == basicString:
+			return x + ` != ""`
+		case kind == basicBool:
+			return x
+		}
+		return x + " != 0"
+	case *ast.ParenExpr:
+		return g.nonEmpty(t.X, x)
+	case *ast.SelectorExpr:
+		return g.nonEmptyImported(t, x)
+	case *ast.StarExpr, *ast.InterfaceType:
+		return x + " != nil"
+	case *ast.ArrayType, *ast.MapType:
+		return "len(" + x + ") != 0"
+	}
+	return ""
+}
+
+// nonEmptyImported is nonEmpty for a type from another package, which is
+// loaded from source to find its underlying type.
+func (g *generator) nonEmptyImported(sel *ast.SelectorExpr, x string) string {
+	text := types.ExprString(sel)
+	pkgName, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	path := g.paths[pkgName.Name]
+	if pkgName.Name == "wowlua" {
+		path = wowluaPath
+	}
+	pkg, ok := g.pkgs[path]
+	if !ok {
+		var err error
+		pkg, err = importer.ForCompiler(token.NewFileSet(), "source", nil).Import(path)
+		if err != nil {
+			g.errs = append(g.errs, fmt.Sprintf("loading %s for omitempty: %v", text, err))
+		}
+		g.pkgs[path] = pkg
+	}
+	if pkg == nil {
+		return ""
+	}
+	obj := pkg.Scope().Lookup(sel.Sel.Name)
+	if obj == nil {
+		g.errs = append(g.errs, fmt.Sprintf("%s not found for omitempty", text))
+		return ""
+	}
+	switch u := obj.Type().Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return x + ` != ""`
+		case u.Info()&types.IsBoolean != 0:
+			return x
+		case u.Info()&(types.IsInteger|types.IsFloat) != 0:
+			return x + " != 0"
+		}
+	case *types.Pointer, *types.Interface:
+		return x + " != nil"
+	case *types.Array, *types.Slice, *types.Map:
+		return "len(" + x + ") != 0"
+	}
+	return ""
+}
+
+// codec returns the name of the codec for typ, queueing it to be written if
+// it's new.
+func (g *generator) codec(typ ast.Expr) string {
+	text := types.ExprString(typ)
+	if c, ok := g.codecs[text]; ok {
+		return c.name
+	}
+	name := mangle(typ)
+	if name == "" {
+		name = "Type"
+	}
+	base := name
+	for i := 2; g.names[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	c := &codec{name: name, typ: typ}
+	g.codecs[text] = c
+	g.names[name] = true
+	g.queue = append(g.queue, c)
+	return name
+}
+
+// mangle returns a name for typ usable in an identifier, or "" if it has
+// no simple one.
+func mangle(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return upperFirst(t.Name)
+	case *ast.SelectorExpr:
+		if x := mangle(t.X); x != "" {
+			return x + upperFirst(t.Sel.Name)
+		}
+	case *ast.StarExpr:
+		if x := mangle(t.X); x != "" {
+			return "Ptr" + x
+		}
+	case *ast.ArrayType:
+		elem := mangle(t.Elt)
+		if elem == "" {
+			return ""
+		}
+		if t.Len == nil {
+			return "Slice" + elem
+		}
+		if lit, ok := t.Len.(*ast.BasicLit); ok {
+			return "Array" + lit.Value + elem
+		}
+	case *ast.MapType:
+		key, value := mangle(t.Key), mangle(t.Value)
+		if key != "" && value != "" {
+			return "Map" + key + value
+		}
+	case *ast.InterfaceType:
+		if len(t.Methods.List) == 0 {
+			return "Interface"
+		}
+	}
+	return ""
+}
+
+func upperFirst(s string) string {
+	for i, r := range s {
+		return string(unicode.ToUpper(r)) + s[i+len(string(r)):]
+	}
+	return s
+}
+
+// writeCodec writes the luaDecode and luaEncode helpers for c.
+func (g *generator) writeCodec(b *bytes.Buffer, c *codec) {
+	text := types.ExprString(c.typ)
+	fmt.Fprintf(b, "func luaDecode%s(n *wowlua.Node, x *%s) error {\n", c.name, text)
+	g.writeDecode(b, c.typ, text)
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "func luaEncode%s(x %s) (*wowlua.Node, error) {\n", c.name, text)
+	g.writeEncode(b, c.typ, text)
+	b.WriteString("}\n\n")
+}
+
+func (g *generator) writeDecode(b *bytes.Buffer, typ ast.Expr, text string) {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		if g.marked[t.Name] {
+			b.WriteString("return x.UnmarshalLua(n)\n")
+			return
+		}
+		kind, ok := basics[t.Name]
+		if !ok {
+			break
+		}
+		switch kind {
+		case basicString:
+			b.WriteString("s, err := n.AsString()\nif err != nil {\nreturn err\n}\n*x = s\nreturn nil\n")
+		case basicBool:
+			b.WriteString("v, err := n.AsBool()\nif err != nil {\nreturn err\n}\n*x = v\nreturn nil\n")
+		case basicInt, basicUint:
+			b.WriteString("i, err := n.AsInt()\nif err != nil {\nreturn err\n}\n")
+			var overflow []string
+			if kind == basicUint {
+				overflow = append(overflow, "i < 0")
+				if text != "uint64" {
+					overflow = append(overflow, fmt.Sprintf("uint64(%s(i)) != uint64(i)", text))
+				}
+			} else if text != "int64" {
+				overflow = append(overflow, fmt.Sprintf("int64(%s(i)) != i", text))
+			}
+			if len(overflow) > 0 {
+				fmt.Fprintf(b, "if %s {\nreturn &wowlua.PathError{Err: wowlua.ErrOverflow}\n}\n", strings.Join(overflow, " || "))
+			}
+			if text == "int64" {
+				b.WriteString("*x = i\nreturn nil\n")
+			} else {
+				fmt.Fprintf(b, "*x = %s(i)\nreturn nil\n", text)
+			}
+		case basicFloat:
+			b.WriteString("f, err := n.AsFloat()\nif err != nil {\nreturn err\n}\n")
+			if text == "float64" {
+				b.WriteString("*x = f\nreturn nil\n")
+			} else {
+				fmt.Fprintf(b, "*x = %s(f)\nreturn nil\n", text)
+			}
+		}
+		return
+	case *ast.StarExpr:
+		if !g.direct(t.X) {
+			break
+		}
+		fmt.Fprintf(b, "if n.IsNil() {\n*x = nil\nreturn nil\n}\nif *x == nil {\n*x = new(%s)\n}\n", types.ExprString(t.X))
+		fmt.Fprintf(b, "return luaDecode%s(n, *x)\n", g.codec(t.X))
+		return
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		b.WriteString("if n.IsNil() {\n*x = nil\nreturn nil\n}\n")
+		b.WriteString("t, err := n.AsTable()\nif err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(b, "s := make(%s, t.SeqLen())\n", text)
+		b.WriteString("for i := range s {\nv := t.GetIndexed(i + 1)\n")
+		fmt.Fprintf(b, "if err := luaDecode%s(v, &s[i]); err != nil {\n", g.codec(t.Elt))
+		b.WriteString("return wowlua.WithPath(err, wowlua.NodeOf(wowlua.IntNumber(int64(i+1))))\n}\n}\n")
+		b.WriteString("*x = s\nreturn nil\n")
+		return
+	case *ast.MapType:
+		if !g.mapKey(t.Key) {
+			break
+		}
+		b.WriteString("if n.IsNil() {\n*x = nil\nreturn nil\n}\n")
+		b.WriteString("t, err := n.AsTable()\nif err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(b, "if *x == nil {\n*x = make(%s, t.Len())\n}\n", text)
+		b.WriteString("t.Range(func(k, v *wowlua.Node) bool {\n")
+		fmt.Fprintf(b, "var key %s\nif err = luaDecode%s(k, &key); err != nil {\nerr = wowlua.WithPath(err, k)\nreturn false\n}\n", types.ExprString(t.Key), g.codec(t.Key))
+		fmt.Fprintf(b, "var value %s\nif err = luaDecode%s(v, &value); err != nil {\nerr = wowlua.WithPath(err, k)\nreturn false\n}\n", types.ExprString(t.Value), g.codec(t.Value))
+		b.WriteString("(*x)[key] = value\nreturn true\n})\nreturn err\n")
+		return
+	}
+	b.WriteString("return n.Decode(x)\n")
+}
+
+func (g *generator) writeEncode(b *bytes.Buffer, typ ast.Expr, text string) {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		if g.marked[t.Name] {
+			b.WriteString("return x.MarshalLua()\n")
+			return
+		}
+		kind, ok := basics[t.Name]
+		if !ok {
+			break
+		}
+		switch kind {
+		case basicString:
+			b.WriteString("return wowlua.NodeOf(wowlua.String(x)), nil\n")
+		case basicBool:
+			b.WriteString("return wowlua.NodeOf(wowlua.Bool(x)), nil\n")
+		case basicInt:
+			if text == "int64" {
+				b.WriteString("return wowlua.NodeOf(wowlua.IntNumber(x)), nil\n")
+			} else {
+				b.WriteString("return wowlua.NodeOf(wowlua.IntNumber(int64(x))), nil\n")
+			}
+		case basicUint:
+			switch text {
+			case "uint", "uint64", "uintptr":
+				b.WriteString("if uint64(x) > 1<<63-1 {\nreturn nil, &wowlua.PathError{Err: wowlua.ErrOverflow}\n}\n")
+			}
+			b.WriteString("return wowlua.NodeOf(wowlua.IntNumber(int64(x))), nil\n")
+		case basicFloat:
+			if text == "float64" {
+				b.WriteString("return wowlua.NodeOf(wowlua.FloatNumber(x)), nil\n")
+			} else {
+				b.WriteString("return wowlua.NodeOf(wowlua.FloatNumber(float64(x))), nil\n")
+			}
+		}
+		return
+	case *ast.StarExpr:
+		if !g.direct(t.X) {
+			break
+		}
+		b.WriteString("if x == nil {\nreturn wowlua.NodeOf(nil), nil\n}\n")
+		fmt.Fprintf(b, "return luaEncode%s(*x)\n", g.codec(t.X))
+		return
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		b.WriteString("if x == nil {\nreturn wowlua.NodeOf(nil), nil\n}\n")
+		b.WriteString("t := wowlua.NewTable()\nfor i, e := range x {\n")
+		b.WriteString("k := wowlua.NodeOf(wowlua.IntNumber(int64(i + 1)))\n")
+		fmt.Fprintf(b, "n, err := luaEncode%s(e)\n", g.codec(t.Elt))
+		b.WriteString("if err != nil {\nreturn nil, wowlua.WithPath(err, k)\n}\nt.Set(k, n)\n}\n")
+		b.WriteString("return wowlua.NodeOf(t), nil\n")
+		return
+	case *ast.MapType:
+		if !g.mapKey(t.Key) {
+			break
+		}
+		key := types.ExprString(t.Key)
+		b.WriteString("if x == nil {\nreturn wowlua.NodeOf(nil), nil\n}\n")
+		fmt.Fprintf(b, "keys := make([]%s, 0, len(x))\nfor k := range x {\nkeys = append(keys, k)\n}\n", key)
+		g.needs["sort"] = true
+		if key == "string" {
+			b.WriteString("sort.Strings(keys)\n")
+		} else {
+			b.WriteString("sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })\n")
+		}
+		b.WriteString("t := wowlua.NewTable()\nfor _, key := range keys {\n")
+		fmt.Fprintf(b, "k, err := luaEncode%s(key)\nif err != nil {\nreturn nil, err\n}\n", g.codec(t.Key))
+		fmt.Fprintf(b, "n, err := luaEncode%s(x[key])\n", g.codec(t.Value))
+		b.WriteString("if err != nil {\nreturn nil, wowlua.WithPath(err, k)\n}\nt.Set(k, n)\n}\n")
+		b.WriteString("return wowlua.NodeOf(t), nil\n")
+		return
+	}
+	b.WriteString("return wowlua.FromGo(x)\n")
+}
+
+// direct returns whether values of typ are decoded and encoded without
+// reflection.
+func (g *generator) direct(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		_, ok := basics[t.Name]
+		return ok || g.marked[t.Name]
+	case *ast.StarExpr:
+		return g.direct(t.X)
+	case *ast.ArrayType:
+		return t.Len == nil && g.direct(t.Elt)
+	case *ast.MapType:
+		return g.mapKey(t.Key) && g.direct(t.Value)
+	}
+	return false
+}
+
+// mapKey returns whether typ is a map key type luacodec handles directly:
+// string or an integer type.
+func (g *generator) mapKey(typ ast.Expr) bool {
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	kind, ok := basics[ident.Name]
+	return ok && (kind == basicString || kind == basicInt || kind == basicUint)
+}
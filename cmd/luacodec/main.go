@@ -0,0 +1,120 @@
+// luacodec generates UnmarshalLua and MarshalLua methods for structs, so they
+// can be decoded from and encoded to wowlua tables without reflection. Mark
+// each struct with a //luacodec:generate comment, or name them with -type,
+// and run it in the package directory, typically from go generate:
+//
+//	//go:generate go run github.com/jasonmf/wowlua/cmd/luacodec
+//
+//	//luacodec:generate
+//	type Event struct {
+//		Title string `lua:"title"`
+//		Day   int    `lua:"day"`
+//	}
+//
+// The methods follow the same rules as wowlua.Unmarshal and wowlua.Marshal:
+// fields are matched by name or `lua` tag, and omitempty and "-" are
+// honored, with omitempty judged by a named type's underlying type. Two
+// fields with the same key are an error. Fields of string, bool and numeric
+// types, marked structs, and slices, maps with string or integer keys and
+// pointers of those are handled directly. Fields of other types, such as
+// time.Time or *wowlua.Table, fall back to n.Decode and wowlua.FromGo. Embedded fields aren't supported,
+// and the generated MarshalLua doesn't detect values that contain themselves.
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jasonmf/wowlua/cmd"
+)
+
+const marker = "//luacodec:generate"
+
+var (
+	fDir   = flag.String("dir", ".", "Package directory")
+	fOut   = flag.String("o", "luacodec_gen.go", "Output file name, in the package directory")
+	fTypes = flag.String("type", "", "Comma-separated struct names to generate methods for, instead of marked ones")
+)
+
+func main() {
+	flag.Parse()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, *fDir, func(info os.FileInfo) bool {
+		name := info.Name()
+		return !strings.HasSuffix(name, "_test.go") && name != filepath.Base(*fOut)
+	}, parser.ParseComments)
+	cmd.FatalIfError(err, "parsing package")
+	if len(pkgs) != 1 {
+		cmd.FatalIfError(errorf("expected one package in %s, found %d", *fDir, len(pkgs)), "parsing package")
+	}
+
+	var names map[string]bool
+	if *fTypes != "" {
+		names = make(map[string]bool)
+		for _, name := range strings.Split(*fTypes, ",") {
+			names[strings.TrimSpace(name)] = true
+		}
+	}
+
+	g := newGenerator()
+	for _, pkg := range pkgs {
+		g.pkg = pkg.Name
+		files := make([]string, 0, len(pkg.Files))
+		for name := range pkg.Files {
+			files = append(files, name)
+		}
+		sort.Strings(files)
+		for _, name := range files {
+			file := pkg.Files[name]
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					g.named[ts.Name.Name] = ts.Type
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					marked := hasMarker(ts.Doc) || (len(gen.Specs) == 1 && hasMarker(gen.Doc))
+					if names != nil {
+						marked = names[ts.Name.Name]
+						delete(names, ts.Name.Name)
+					}
+					if marked {
+						g.addStruct(ts.Name.Name, st, file)
+					}
+				}
+			}
+		}
+	}
+	for name := range names {
+		cmd.FatalIfError(errorf("struct %s not found", name), "finding types")
+	}
+
+	src, err := g.generate()
+	cmd.FatalIfError(err, "generating code")
+	err = ioutil.WriteFile(filepath.Join(*fDir, *fOut), src, 0644)
+	cmd.FatalIfError(err, "writing output")
+}
+
+func hasMarker(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == marker {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+// luafmt rewrites SavedVariables files in canonical form so that the same
+// data always produces the same file. With no files it formats standard
+// input to standard output. Like gofmt, it reports a file it can't format
+// and goes on to the rest, exiting with status 1 at the end.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jasonmf/wowlua"
+	"github.com/jasonmf/wowlua/cmd"
+)
+
+var (
+	fList  = flag.Bool("l", false, "List files whose formatting differs from luafmt's")
+	fWrite = flag.Bool("w", false, "Write result to the source file instead of stdout")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		cmd.FatalIfError(err, "reading stdin")
+		out, err := format(b)
+		cmd.FatalIfError(err, "formatting stdin")
+		_, err = os.Stdout.Write(out)
+		cmd.FatalIfError(err, "writing output")
+		return
+	}
+	status := 0
+	for _, name := range flag.Args() {
+		if err := formatFile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func formatFile(name string) error {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	out, err := format(b)
+	if err != nil {
+		return err
+	}
+	changed := !bytes.Equal(b, out)
+	if *fList && changed {
+		fmt.Println(name)
+	}
+	if *fWrite {
+		if !changed {
+			return nil
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(name, out, info.Mode().Perm())
+	}
+	if !*fList {
+		_, err = os.Stdout.Write(out)
+	}
+	return err
+}
+
+func format(b []byte) ([]byte, error) {
+	table, err := wowlua.ParseLua(string(b))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := wowlua.EncodeCanonical(&out, table); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
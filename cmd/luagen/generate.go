@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	"github.com/jasonmf/wowlua"
+)
+
+type generator struct {
+	maps []string // Paths of tables to make maps
+}
+
+// source returns the formatted Go source of a file in package pkg declaring
+// typeName for the samples, which were read from the files named.
+func (g *generator) source(pkg, typeName string, names []string, samples []*wowlua.Table) ([]byte, error) {
+	root := &shape{}
+	for _, t := range samples {
+		root.observe(wowlua.NewNode(wowlua.NodeTypeTable, t))
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "// %s was generated by luagen from %s.\n", typeName, strings.Join(names, ", "))
+	fmt.Fprintf(&b, "type %s %s\n", typeName, g.goType(root, nil))
+	return format.Source(b.Bytes())
+}
+
+// goType returns the Go type for a shape found at path. Elements of slices
+// and maps have "*" as their key in the path.
+func (g *generator) goType(s *shape, path []string) string {
+	if s.kinds&kindTable == 0 {
+		return scalarType(s.kinds)
+	}
+	if s.kinds != kindTable {
+		return "interface{}" // Tables mixed with other values
+	}
+	switch {
+	case s.keyed == 0 && s.sequences == 0:
+		return "interface{}" // Only ever empty
+	case s.keyed == 0:
+		return "[]" + g.goType(s.elem, child(path, "*"))
+	case s.sequences == 0 && s.keyKinds == kindString && s.allLuaNames && !g.isMap(path):
+		return g.structType(s, path)
+	}
+	keyKinds := s.keyKinds
+	if s.sequences > 0 {
+		keyKinds |= kindInt
+	}
+	key := scalarType(keyKinds)
+	if keyKinds&kindTable != 0 {
+		key = "interface{}"
+	}
+	return "map[" + key + "]" + g.goType(s.values(), child(path, "*"))
+}
+
+func (g *generator) structType(s *shape, path []string) string {
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	used := make(map[string]bool)
+	for _, key := range s.fieldOrder {
+		f := s.fields[key]
+		name := goName(key.text)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s%d", goName(key.text), i)
+		}
+		used[name] = true
+		tag := key.text
+		if f.seen < s.tables {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "%s %s `lua:%q`\n", name, g.goType(f.shape, child(path, key.text)), tag)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func child(path []string, key string) []string {
+	return append(path[:len(path):len(path)], key)
+}
+
+// isMap returns whether path matches one of the -map paths.
+func (g *generator) isMap(path []string) bool {
+	for _, pattern := range g.maps {
+		parts := strings.Split(pattern, "/")
+		if len(parts) != len(path) {
+			continue
+		}
+		match := true
+		for i, part := range parts {
+			if part != "*" && part != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func scalarType(k kind) string {
+	switch k {
+	case kindString:
+		return "string"
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "int"
+	case kindFloat, kindInt | kindFloat:
+		return "float64"
+	}
+	return "interface{}"
+}
+
+// goName converts a key to an exported Go identifier: "nowYear" becomes
+// "NowYear" and "HarbingerTools_Events" becomes "HarbingerToolsEvents".
+func goName(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteByte('F')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "Field"
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jasonmf/wowlua"
+)
+
+const source_header = "package saved\n\n// SavedVariables was generated by luagen from Addon.lua.\n"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		maps     []string
+		samples  []string
+		expected string
+	}{
+		{"struct", nil, []string{`Addon = { ["nowYear"] = 2014, ["title"] = "Raid", ["scale"] = 1.5, ["alts"] = { "a", "b" } }`}, `type SavedVariables struct {
+	Addon struct {
+		NowYear int      ` + "`lua:\"nowYear\"`" + `
+		Title   string   ` + "`lua:\"title\"`" + `
+		Scale   float64  ` + "`lua:\"scale\"`" + `
+		Alts    []string ` + "`lua:\"alts\"`" + `
+	} ` + "`lua:\"Addon\"`" + `
+}
+`},
+		{"omitempty", nil, []string{`Addon = { ["a"] = 1, ["b"] = true }`, `Addon = { ["a"] = 2 }`}, `type SavedVariables struct {
+	Addon struct {
+		A int  ` + "`lua:\"a\"`" + `
+		B bool ` + "`lua:\"b,omitempty\"`" + `
+	} ` + "`lua:\"Addon\"`" + `
+}
+`},
+		{"keyword keys", nil, []string{`Addon = { ["end"] = 1, ["start"] = 2 }`}, `type SavedVariables struct {
+	Addon map[string]int ` + "`lua:\"Addon\"`" + `
+}
+`},
+		{"name keys", nil, []string{`Addon = { ["Moon Guard"] = { 1, 2 } }`}, `type SavedVariables struct {
+	Addon map[string][]int ` + "`lua:\"Addon\"`" + `
+}
+`},
+		{"number and string keys", nil, []string{`Addon = { [1] = "one", ["1"] = "string one", [2.5] = "half" }`}, `type SavedVariables struct {
+	Addon map[interface{}]string ` + "`lua:\"Addon\"`" + `
+}
+`},
+		{"bool keys", nil, []string{`Addon = { [true] = "yes", [false] = "no" }`}, `type SavedVariables struct {
+	Addon map[bool]string ` + "`lua:\"Addon\"`" + `
+}
+`},
+		{"map flag", []string{"Addon"}, []string{`Addon = { ["Volne"] = { ["level"] = 90 }, ["Tesk"] = { ["level"] = 12, ["gold"] = 1.5 } }`}, `type SavedVariables struct {
+	Addon map[string]struct {
+		Level int     ` + "`lua:\"level\"`" + `
+		Gold  float64 ` + "`lua:\"gold,omitempty\"`" + `
+	} ` + "`lua:\"Addon\"`" + `
+}
+`},
+	}
+	for _, test := range tests {
+		var samples []*wowlua.Table
+		for _, sample := range test.samples {
+			table, err := wowlua.ParseLua(sample)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing %s sample: %v", test.name, err)
+			}
+			samples = append(samples, table)
+		}
+		g := &generator{maps: test.maps}
+		src, err := g.source("saved", "SavedVariables", []string{"Addon.lua"}, samples)
+		if err != nil {
+			t.Errorf("Unexpected %s error: %v", test.name, err)
+			continue
+		}
+		if expected := source_header + test.expected; string(src) != expected {
+			t.Errorf("Expected %s source\n%s\ngot\n%s", test.name, expected, src)
+		}
+	}
+}
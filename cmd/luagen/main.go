@@ -0,0 +1,74 @@
+// luagen generates Go struct definitions for SavedVariables files, for use
+// with wowlua.Unmarshal. It reads one or more sample files, infers a type for
+// every value and prints the definitions, which are a starting point to be
+// edited rather than a finished API.
+//
+// Tables whose keys are all Lua names become structs; fields missing from
+// some samples are tagged omitempty. Sequences become slices. Tables with
+// other keys, such as realm names, become maps, as do tables whose paths are
+// given with -map, which is useful for tables keyed by character names:
+//
+//	luagen -map 'HarbingerTools_Events/Characters/*' HarbingerTools.lua
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jasonmf/wowlua"
+	"github.com/jasonmf/wowlua/cmd"
+)
+
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+var (
+	fPackage = flag.String("pkg", "main", "Package name for the generated file")
+	fType    = flag.String("type", "SavedVariables", "Name of the generated type")
+	fOut     = flag.String("o", "", "Output file; standard output if empty")
+	fMaps    pathList
+)
+
+func main() {
+	flag.Var(&fMaps, "map", "Path of a table to make a map, with * matching any key; may be repeated")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: luagen [flags] sample.lua...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	var names []string
+	var samples []*wowlua.Table
+	for _, name := range flag.Args() {
+		b, err := ioutil.ReadFile(name)
+		cmd.FatalIfError(err, "reading "+name)
+		table, err := wowlua.ParseLua(string(b))
+		cmd.FatalIfError(err, "parsing "+name)
+		samples = append(samples, table)
+		names = append(names, filepath.Base(name))
+	}
+
+	g := &generator{maps: fMaps}
+	src, err := g.source(*fPackage, *fType, names, samples)
+	cmd.FatalIfError(err, "formatting output")
+
+	if *fOut == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = ioutil.WriteFile(*fOut, src, 0644)
+	}
+	cmd.FatalIfError(err, "writing output")
+}
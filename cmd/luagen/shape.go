@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jasonmf/wowlua"
+)
+
+// kind is a set of the kinds of value observed at a place in the samples.
+type kind int
+
+const (
+	kindString kind = 1 << iota
+	kindBool
+	kindInt
+	kindFloat
+	kindTable
+)
+
+// A shape accumulates what's been observed at one place in the samples:
+// every value at that place is merged into a single shape.
+type shape struct {
+	kinds kind
+
+	// Tables
+	tables      int    // Tables observed, including empty ones
+	sequences   int    // Non-empty tables whose keys are exactly 1 to n
+	keyed       int    // Non-empty tables with other keys
+	elem        *shape // Values of sequences
+	keyKinds    kind   // Kinds of keys of keyed tables
+	fields      map[fieldKey]*field
+	fieldOrder  []fieldKey
+	allLuaNames bool // Whether every key of keyed tables is a string that's a Lua name
+}
+
+// A fieldKey identifies a key of keyed tables by its kind and value, so
+// keys that print the same, like 1 and "1", stay apart.
+type fieldKey struct {
+	kind kind
+	text string
+}
+
+// A field is an entry of keyed tables.
+type field struct {
+	key   fieldKey
+	seen  int // Tables it appeared in
+	shape *shape
+}
+
+func (s *shape) observe(n *wowlua.Node) {
+	switch n.GetType() {
+	case wowlua.NodeTypeString, wowlua.NodeTypeIdentifier:
+		s.kinds |= kindString
+	case wowlua.NodeTypeBool:
+		s.kinds |= kindBool
+	case wowlua.NodeTypeNumber:
+		if n.IsInteger() {
+			s.kinds |= kindInt
+		} else {
+			s.kinds |= kindFloat
+		}
+	case wowlua.NodeTypeTable:
+		s.observeTable(n.GetTable())
+	}
+}
+
+func (s *shape) observeTable(t *wowlua.Table) {
+	s.kinds |= kindTable
+	s.tables++
+	switch seqLen := t.SeqLen(); {
+	case t.Len() == 0:
+	case seqLen == t.Len():
+		s.sequences++
+		if s.elem == nil {
+			s.elem = &shape{}
+		}
+		for _, k := range t.Keys() {
+			s.elem.observe(t.Get(k))
+		}
+	default:
+		if s.keyed == 0 {
+			s.allLuaNames = true
+		}
+		s.keyed++
+		for _, k := range t.Keys() {
+			var key fieldKey
+			switch k.GetType() {
+			case wowlua.NodeTypeString, wowlua.NodeTypeIdentifier:
+				s.keyKinds |= kindString
+				key = fieldKey{kindString, k.GetString()}
+				if !wowlua.IsName(key.text) {
+					s.allLuaNames = false
+				}
+			case wowlua.NodeTypeNumber:
+				s.allLuaNames = false
+				if k.IsInteger() {
+					s.keyKinds |= kindInt
+				} else {
+					s.keyKinds |= kindFloat
+				}
+				// Integer-valued floats are the same keys as integers
+				n := k.GetNumber()
+				if i, ok := n.Int64(); ok {
+					key = fieldKey{kindInt, strconv.FormatInt(i, 10)}
+				} else {
+					key = fieldKey{kindFloat, strconv.FormatFloat(n.Float64(), 'g', -1, 64)}
+				}
+			case wowlua.NodeTypeBool:
+				s.allLuaNames = false
+				s.keyKinds |= kindBool
+				key = fieldKey{kindBool, strconv.FormatBool(k.GetBool())}
+			default:
+				s.allLuaNames = false
+				s.keyKinds |= kindTable
+				key = fieldKey{kindTable, fmt.Sprintf("%p", k.GetTable())}
+			}
+			f := s.field(key)
+			f.seen++
+			f.shape.observe(t.Get(k))
+		}
+	}
+}
+
+// field returns the field for key, adding it if it's new.
+func (s *shape) field(key fieldKey) *field {
+	if s.fields == nil {
+		s.fields = make(map[fieldKey]*field)
+	}
+	f, ok := s.fields[key]
+	if !ok {
+		f = &field{key: key, shape: &shape{}}
+		s.fields[key] = f
+		s.fieldOrder = append(s.fieldOrder, key)
+	}
+	return f
+}
+
+// merge adds everything observed in o to s.
+func (s *shape) merge(o *shape) {
+	if o == nil {
+		return
+	}
+	s.kinds |= o.kinds
+	s.tables += o.tables
+	s.sequences += o.sequences
+	if o.keyed > 0 {
+		if s.keyed == 0 {
+			s.allLuaNames = o.allLuaNames
+		} else {
+			s.allLuaNames = s.allLuaNames && o.allLuaNames
+		}
+	}
+	s.keyed += o.keyed
+	s.keyKinds |= o.keyKinds
+	if o.elem != nil {
+		if s.elem == nil {
+			s.elem = &shape{}
+		}
+		s.elem.merge(o.elem)
+	}
+	for _, key := range o.fieldOrder {
+		of := o.fields[key]
+		f := s.field(key)
+		f.seen += of.seen
+		f.shape.merge(of.shape)
+	}
+}
+
+// values returns a shape merging every field and sequence element, for the
+// values of a map.
+func (s *shape) values() *shape {
+	v := &shape{}
+	v.merge(s.elem)
+	for _, key := range s.fieldOrder {
+		v.merge(s.fields[key].shape)
+	}
+	return v
+}
@@ -4,7 +4,6 @@ import (
 	"flag"
 	"io/ioutil"
 	"log"
-	"strings"
 
 	"github.com/jasonmf/wowlua"
 	"github.com/jasonmf/wowlua/cmd"
@@ -12,7 +11,7 @@ import (
 
 var (
 	fInPath = flag.String("in", "", "Input file")
-	fPath   = flag.String("path", "", "Nav path")
+	fPath   = flag.String("path", "", `Nav path, e.g. Characters/"Moon Guard"/Volne[1]`)
 )
 
 func main() {
@@ -23,8 +22,9 @@ func main() {
 	table, err := wowlua.ParseLua(string(b))
 	cmd.FatalIfError(err, "parsing")
 
-	pathElem := strings.Split(*fPath, "/")
-	_, node, err := table.GetStringPath(pathElem...)
+	path, err := wowlua.ParsePath(*fPath)
+	cmd.FatalIfError(err, "parsing path")
+	_, node, err := table.GetPath(path...)
 	cmd.FatalIfError(err, "getting element")
 	log.Println(node)
 }
@@ -0,0 +1,324 @@
+package wowlua
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrNotPointer indicates a decode target that isn't a non-nil pointer
+	ErrNotPointer = errors.New("decode target must be a non-nil pointer")
+	// ErrOverflow indicates a number too large for the Go type decoded into
+	ErrOverflow = errors.New("Number overflows the Go type")
+	// ErrUnknownField indicates a table entry with no matching struct field
+	// when a Decoder disallows them
+	ErrUnknownField = errors.New("unknown field")
+)
+
+// LuaUnmarshaler is implemented by types that can decode themselves from a
+// node. The node is Nil for a nil value.
+type LuaUnmarshaler interface {
+	UnmarshalLua(*Node) error
+}
+
+var (
+	nodeType   = reflect.TypeOf((*Node)(nil))
+	tableType  = reflect.TypeOf((*Table)(nil))
+	numberType = reflect.TypeOf(Number{})
+)
+
+// Unmarshal parses SavedVariables data and stores the result in the value
+// pointed to by v. The top-level assignments are decoded like the entries
+// of a table, so v is typically a pointer to a struct with a field for each
+// saved variable. See Table.Decode for how values are converted.
+func Unmarshal(data []byte, v interface{}) error {
+	t, err := ParseLua(string(data))
+	if err != nil {
+		return err
+	}
+	return t.Decode(v)
+}
+
+// Decode stores the table in the value pointed to by v, converting it the
+// way encoding/json does with these rules:
+//
+// Struct fields are filled from the entry whose key is the field name, or
+// the name given by a `lua:"key"` tag. A tag of "-" skips the field and tag
+// options such as omitempty are ignored. Fields of untagged embedded structs
+// are treated as fields of the outer struct. Entries with no matching field
+// are ignored.
+//
+// Slices and arrays are filled from the sequence part of a table, so Lua's
+// index 1 becomes Go's index 0. Maps may have any key type a key can be
+// decoded into, such as string, int or float64.
+//
+// Numbers decode into any Go numeric type they fit in; a float decodes into
+// an integer type only if it has an integer value. Strings, bools and
+// numbers decode into the Go types of the same kind.
+//
+// Into an interface{}, values are converted as Table.ToGo does.
+//
+// Fields of type *Node, *Table or Number receive the value unconverted.
+//
+// Types that implement LuaUnmarshaler decode themselves, as map keys too.
+// Otherwise types that implement encoding.TextUnmarshaler decode strings
+// with UnmarshalText.
+//
+// nil sets pointers, maps, slices and interfaces to nil and leaves other
+// values unchanged. Pointers are allocated as needed.
+//
+// Errors are *PathErrors naming the path of the value that couldn't be
+// decoded. Decoding doesn't stop at the first bad value; the first error is
+// returned after the rest have been decoded.
+func (t *Table) Decode(v interface{}) error {
+	return NewNode(NodeTypeTable, t).Decode(v)
+}
+
+// Decode stores the node's value in the value pointed to by v, converting it
+// as Table.Decode does.
+func (n *Node) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrNotPointer
+	}
+	d := &decoder{}
+	d.value(nil, n, rv.Elem())
+	return d.err
+}
+
+type decoder struct {
+	err                   error // The first error
+	disallowUnknownFields bool
+	useNumber             bool
+}
+
+// fail records err, giving it path if it doesn't have one.
+func (d *decoder) fail(path Path, err error) {
+	if d.err == nil {
+		d.err = WithPath(err, path...)
+	}
+}
+
+// value decodes n into rv, which must be settable.
+func (d *decoder) value(path Path, n *Node, rv reflect.Value) {
+	switch rv.Type() {
+	case nodeType:
+		rv.Set(reflect.ValueOf(n))
+		return
+	case tableType:
+		if n.IsNil() {
+			rv.Set(reflect.Zero(rv.Type()))
+			return
+		}
+		t, err := n.AsTable()
+		if err != nil {
+			d.fail(path, err)
+			return
+		}
+		rv.Set(reflect.ValueOf(t))
+		return
+	case numberType:
+		if err := n.checkType(NodeTypeNumber); err != nil {
+			d.fail(path, err)
+			return
+		}
+		rv.Set(reflect.ValueOf(n.GetNumber()))
+		return
+	}
+
+	if rv.Kind() != reflect.Ptr && d.custom(path, n, rv) {
+		return
+	}
+	if n.IsNil() {
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		d.value(path, n, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			d.fail(path, fmt.Errorf("can't decode %v into %v", n.GetType(), rv.Type()))
+			return
+		}
+		rv.Set(reflect.ValueOf(d.goValue(n)))
+	case reflect.String:
+		s, err := n.AsString()
+		if err != nil {
+			d.fail(path, err)
+			return
+		}
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := n.AsBool()
+		if err != nil {
+			d.fail(path, err)
+			return
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := n.AsInt()
+		if err == nil && rv.OverflowInt(i) {
+			err = &PathError{Err: ErrOverflow}
+		}
+		if err != nil {
+			d.fail(path, err)
+			return
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := n.AsInt()
+		if err == nil && (i < 0 || rv.OverflowUint(uint64(i))) {
+			err = &PathError{Err: ErrOverflow}
+		}
+		if err != nil {
+			d.fail(path, err)
+			return
+		}
+		rv.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := n.AsFloat()
+		if err != nil {
+			d.fail(path, err)
+			return
+		}
+		rv.SetFloat(f)
+	case reflect.Struct:
+		if t := d.table(path, n); t != nil {
+			d.structValue(path, t, rv)
+		}
+	case reflect.Map:
+		if t := d.table(path, n); t != nil {
+			d.mapValue(path, t, rv)
+		}
+	case reflect.Slice:
+		if t := d.table(path, n); t != nil {
+			seqLen := t.SeqLen()
+			if rv.IsNil() || rv.Cap() < seqLen {
+				rv.Set(reflect.MakeSlice(rv.Type(), seqLen, seqLen))
+			}
+			rv.SetLen(seqLen)
+			d.sequence(path, t, rv)
+		}
+	case reflect.Array:
+		if t := d.table(path, n); t != nil {
+			d.sequence(path, t, rv)
+			zero := reflect.Zero(rv.Type().Elem())
+			for i := t.SeqLen(); i < rv.Len(); i++ {
+				rv.Index(i).Set(zero)
+			}
+		}
+	default:
+		d.fail(path, fmt.Errorf("can't decode %v into %v", n.GetType(), rv.Type()))
+	}
+}
+
+// custom decodes n with rv's LuaUnmarshaler or encoding.TextUnmarshaler
+// method and returns whether it had one.
+func (d *decoder) custom(path Path, n *Node, rv reflect.Value) bool {
+	if !rv.CanAddr() || !rv.Addr().CanInterface() {
+		return false
+	}
+	switch u := rv.Addr().Interface().(type) {
+	case LuaUnmarshaler:
+		if err := u.UnmarshalLua(n); err != nil {
+			d.fail(path, err)
+		}
+		return true
+	case encoding.TextUnmarshaler:
+		s, err := n.AsString()
+		if err != nil {
+			return false
+		}
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			d.fail(path, err)
+		}
+		return true
+	}
+	return false
+}
+
+// table returns n's table, or records an error and returns nil.
+func (d *decoder) table(path Path, n *Node) *Table {
+	t, err := n.AsTable()
+	if err != nil {
+		d.fail(path, err)
+		return nil
+	}
+	return t
+}
+
+func (d *decoder) structValue(path Path, t *Table, rv reflect.Value) {
+	fields := structFields(rv.Type())
+	if d.disallowUnknownFields {
+		for _, e := range t.entries {
+			known := false
+			for _, f := range fields {
+				if e.key.GetType() == NodeTypeString && e.key.GetString() == f.name {
+					known = true
+					break
+				}
+			}
+			if !known {
+				d.fail(path.child(e.key), ErrUnknownField)
+			}
+		}
+	}
+	for _, f := range fields {
+		k := NewNode(NodeTypeString, f.name)
+		n := t.Get(k)
+		if n == nil {
+			continue
+		}
+		d.value(path.child(k), n, rv.FieldByIndex(f.index))
+	}
+}
+
+func (d *decoder) mapValue(path Path, t *Table, rv reflect.Value) {
+	mt := rv.Type()
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(mt, t.Len()))
+	}
+	for _, e := range t.entries {
+		kv := reflect.New(mt.Key()).Elem()
+		keys := &decoder{disallowUnknownFields: d.disallowUnknownFields, useNumber: d.useNumber}
+		keys.value(path.child(e.key), e.key, kv)
+		if keys.err != nil {
+			if d.err == nil {
+				d.err = keys.err
+			}
+			continue
+		}
+		vv := reflect.New(mt.Elem()).Elem()
+		d.value(path.child(e.key), e.value, vv)
+		rv.SetMapIndex(kv, vv)
+	}
+}
+
+// sequence decodes the sequence part of t into the elements of rv, a slice
+// or array, as far as rv's length allows.
+func (d *decoder) sequence(path Path, t *Table, rv reflect.Value) {
+	seqLen := t.SeqLen()
+	for i := 0; i < seqLen && i < rv.Len(); i++ {
+		k := intKey(i + 1)
+		d.value(path.child(k), t.Get(k), rv.Index(i))
+	}
+}
+
+// goValue returns n as a plain Go value for an interface{}.
+func (d *decoder) goValue(n *Node) interface{} {
+	opts := GoOptions{}
+	if d.useNumber {
+		opts.Numbers = NumbersAsNumber
+	}
+	return opts.value(n, make(map[*Table]interface{}))
+}
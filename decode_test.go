@@ -0,0 +1,114 @@
+package wowlua
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type calendarEvent struct {
+	Title    string `lua:"title"`
+	Day      int    `lua:"day"`
+	Month    int8   `lua:"month"`
+	Calendar string `lua:"calendarType,omitempty"`
+	Ignored  string `lua:"-"`
+}
+
+type savedVariables struct {
+	Events struct {
+		Characters map[string]map[string][]calendarEvent
+	} `lua:"HarbingerTools_Events"`
+	GuildLog map[string]map[string][]*struct {
+		Type    string `lua:"type"`
+		Player1 string `lua:"player1"`
+		Rest    map[string]interface{}
+	} `lua:"HarbingerTools_GuildLog"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	var sv savedVariables
+	if err := Unmarshal([]byte(sample_data), &sv); err != nil {
+		t.Fatalf("Unexpected error unmarshaling: %v", err)
+	}
+	tab, _ := ParseLua(sample_data)
+
+	events := sv.Events.Characters["Moon Guard"]["Volne"]
+	volne, _ := tab.AsTable(NewPath("HarbingerTools_Events", "Characters", "Moon Guard", "Volne")...)
+	seq_len := volne.SeqLen()
+	if len(events) != seq_len {
+		t.Fatalf("Expected %d events, got %d", seq_len, len(events))
+	}
+	expected := calendarEvent{Title: "Hallow's End", Day: 18, Month: -1, Calendar: "HOLIDAY"}
+	if events[0] != expected {
+		t.Errorf("Expected first event %+v, got %+v", expected, events[0])
+	}
+
+	log := sv.GuildLog["Moon Guard"]["Harbingers of Discord"]
+	if len(log) == 0 || log[0].Type != "promote" || log[0].Player1 != "Jasbyn" {
+		t.Errorf("Expected first guild log entry to be Jasbyn's promotion, got %+v", log)
+	}
+}
+
+func TestDecodeInterface(t *testing.T) {
+	tab := Map("seq", Seq("a", 2, 2.5, true), "map", Map(1, "x", "y", Seq()))
+	var v interface{}
+	if err := tab.Decode(&v); err != nil {
+		t.Fatalf("Unexpected error decoding: %v", err)
+	}
+	expected := map[string]interface{}{
+		"seq": []interface{}{"a", int64(2), 2.5, true},
+		"map": map[interface{}]interface{}{int64(1): "x", "y": map[string]interface{}{}},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, v)
+	}
+}
+
+type decodeInts struct {
+	A struct {
+		B []int8 `lua:"b"`
+	} `lua:"a"`
+}
+
+type decodeFloats struct {
+	A struct {
+		B []int `lua:"b"`
+	} `lua:"a"`
+}
+
+type decodeC struct {
+	C int `lua:"c"`
+}
+
+type decodeSeqC struct {
+	C []string `lua:"c"`
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tab := Map("a", Map("b", Seq(1, 300, 2.5)), "c", "str")
+	tests := []struct {
+		target   interface{}
+		path     string
+		expected error
+	}{
+		{&decodeInts{}, "a/b[2]", ErrOverflow},
+		{&decodeFloats{}, "a/b[3]", ErrNotInteger},
+		{&decodeC{}, "c", ErrWrongType},
+		{&decodeSeqC{}, "c", ErrNotTable},
+		{&map[string]map[int]string{}, "a/b", ErrWrongType},
+	}
+	for _, test := range tests {
+		err := tab.Decode(test.target)
+		var path_err *PathError
+		if !errors.As(err, &path_err) || !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v decoding into %T, got %v", test.expected, test.target, err)
+			continue
+		}
+		if path_err.Path.String() != test.path {
+			t.Errorf("Expected error at %s, got %s", test.path, path_err.Path)
+		}
+	}
+	if err := tab.Decode(struct{}{}); err != ErrNotPointer {
+		t.Errorf("Expected ErrNotPointer, got %v", err)
+	}
+}
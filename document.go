@@ -0,0 +1,781 @@
+package wowlua
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrMixedSequence indicates a Document edit that would shift values written
+// without keys past holes or entries written with integer keys, which a
+// Table would shift differently
+var ErrMixedSequence = errors.New("sequence has holes or values written with keys")
+
+// A Document is a concrete syntax tree of a Lua file that keeps everything
+// ParseLua throws away: comments, blank lines, key order and the spelling of
+// every number and string. It's for editing files people maintain by hand.
+// Edits replace only the text of the entries they touch, so the rest of the
+// file comes back out of Bytes exactly as it went in.
+//
+// The tree records the position of each token in the file. Whitespace and
+// comments between tokens are trivia that belong to no entry; they're kept
+// as they are because the original text is.
+type Document struct {
+	// Style is used to encode keys and values added by edits. ParseDocument
+	// sets it to match the document where it can: the indent is taken from
+	// the first indented line and keys are bare if any key in the document
+	// is.
+	Style EncoderOptions
+
+	src    string
+	fields []*cstField // Top-level assignments
+}
+
+type cstTable struct {
+	open   int // Offset of the '{'
+	close  int // Offset of the '}'
+	fields []*cstField
+}
+
+// A cstField is an entry in a table constructor, or a top-level assignment.
+type cstField struct {
+	start      int // Offset of the field's first token
+	key        *Node
+	positional bool // Written without a key
+	valueStart int
+	valueEnd   int
+	valueType  NodeType
+	table      *cstTable // The value, if it's a table constructor
+	sep        int       // Offset of the separator that follows, or -1
+}
+
+// A textEdit replaces src[start:end] with text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// ParseDocument parses Lua assignments into a Document.
+func ParseDocument(data string) (*Document, error) {
+	fields, bareKeys, err := parseCST(data)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{
+		src:    data,
+		fields: fields,
+		Style: EncoderOptions{
+			Indent:         detectIndent(data),
+			BareKeys:       bareKeys,
+			TrailingCommas: true,
+		},
+	}
+	return d, nil
+}
+
+// Bytes returns the document's text.
+func (d *Document) Bytes() []byte {
+	return []byte(d.src)
+}
+
+func (d *Document) String() string {
+	return d.src
+}
+
+// Table parses the document's current text as ParseLua does.
+func (d *Document) Table() (*Table, error) {
+	return ParseLua(d.src)
+}
+
+// SetValue sets the value at path. An existing entry keeps its key and the
+// text around it; only the value is rewritten. A missing entry is added at
+// the end of its table, or of the document for a top-level name. The tables
+// leading to it must already exist. Setting a nil value deletes the entry,
+// except that a value written without a key is replaced by nil, so the ones
+// after it keep their positions as they do in a Table.
+func (d *Document) SetValue(path Path, n *Node) error {
+	if len(path) == 0 {
+		return ErrEmptyPath
+	}
+	parent, f, err := d.find(path)
+	if err != nil {
+		return err
+	}
+	if n == nil || n.IsNil() {
+		switch {
+		case f == nil:
+			return nil
+		case !f.positional:
+			return d.Delete(path)
+		}
+		n = NewNode(NodeTypeNil, nil)
+	}
+	if f != nil {
+		text, err := d.encode(path, n, lineIndent(d.src, f.start))
+		if err != nil {
+			return err
+		}
+		return d.apply(textEdit{f.valueStart, f.valueEnd, text})
+	}
+	if parent != nil {
+		return d.appendField(parent, path, n)
+	}
+
+	k := path[0]
+	if k.GetType() != NodeTypeString || !IsName(k.GetString()) {
+		return &PathError{Path: path.clone(), Err: ErrNotName}
+	}
+	text, err := d.encode(path, n, "")
+	if err != nil {
+		return err
+	}
+	text = k.GetString() + " = " + text + "\n"
+	if d.src != "" && !strings.HasSuffix(d.src, "\n") {
+		text = "\n" + text
+	}
+	return d.apply(textEdit{len(d.src), len(d.src), text})
+}
+
+// Delete removes the entry at path. If the entry is alone on its lines, the
+// lines are removed, along with any comment that ends the last of them.
+// Deleting a value written without a key shifts the following ones down,
+// like Table.Remove; if that would skip holes or entries written with
+// integer keys, Delete returns an error wrapping ErrMixedSequence.
+func (d *Document) Delete(path Path) error {
+	if len(path) == 0 {
+		return ErrEmptyPath
+	}
+	parent, f, err := d.find(path)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return &PathError{Path: path.clone(), Err: ErrNotFound}
+	}
+	fields := d.fields
+	if parent != nil {
+		fields = parent.fields
+	}
+	if f.positional {
+		pos, _ := f.key.GetNumber().Int64()
+		if err := checkShift(parent, pos, path); err != nil {
+			return err
+		}
+	}
+	var prev *cstField
+	for i, sibling := range fields {
+		if sibling == f && i > 0 {
+			prev = fields[i-1]
+		}
+	}
+
+	start, end := f.start, f.valueEnd
+	if f.sep >= 0 {
+		end = f.sep + 1
+	}
+	ls := lineStart(d.src, start)
+	le := lineEnd(d.src, end)
+	switch {
+	case isBlank(d.src[ls:start]) && isTrivia(d.src[end:le]):
+		start = ls
+		end = le
+		if end < len(d.src) {
+			end++
+		}
+	case f.sep >= 0:
+		end += len(d.src[end:]) - len(strings.TrimLeft(d.src[end:], " \t"))
+	case prev != nil && prev.sep >= 0:
+		// The last entry on a line: take the separator before it instead
+		start = prev.sep
+	}
+	return d.apply(textEdit{start, end, ""})
+}
+
+// Insert adds n at position pos in the sequence of values written without
+// keys in the table at the path's parent, where pos is the last key of the
+// path. Values at pos and after are shifted up, like Table.Insert. A pos one
+// past the last such value appends. If the table has a nil value without a
+// key, or an entry written with an integer key, at pos or after, Insert
+// returns an error wrapping ErrMixedSequence, since shifting only the
+// values without keys wouldn't do what Table.Insert does.
+func (d *Document) Insert(path Path, n *Node) error {
+	if len(path) == 0 {
+		return ErrEmptyPath
+	}
+	last := path[len(path)-1]
+	pos, ok := last.GetNumber().Int64()
+	if last.GetType() != NodeTypeNumber || !ok || len(path) == 1 {
+		return &PathError{Path: path.clone(), Err: ErrOutOfRange}
+	}
+	_, f, err := d.find(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return &PathError{Path: path[:len(path)-1].clone(), Err: ErrNotFound}
+	}
+	if f.table == nil {
+		return &PathError{Path: path[:len(path)-1].clone(), Err: ErrNotTable, Expected: NodeTypeTable, Actual: f.valueType}
+	}
+	if err := checkShift(f.table, pos, path); err != nil {
+		return err
+	}
+	var positional []*cstField
+	for _, field := range f.table.fields {
+		if field.positional {
+			positional = append(positional, field)
+		}
+	}
+	switch {
+	case pos < 1 || pos > int64(len(positional))+1:
+		return &PathError{Path: path.clone(), Err: ErrOutOfRange}
+	case pos <= int64(len(positional)):
+		return d.insertBefore(positional[pos-1], path, n)
+	}
+	return d.appendField(f.table, path, n)
+}
+
+// checkShift returns an error if shifting the values without keys in t from
+// position pos on wouldn't shift t's sequence the way Table.Insert and
+// Table.Remove do.
+func checkShift(t *cstTable, pos int64, path Path) error {
+	for _, f := range t.fields {
+		i, ok := f.key.GetNumber().Int64()
+		if f.key.GetType() != NodeTypeNumber || !ok || i < pos {
+			continue
+		}
+		if !f.positional || f.valueType == NodeTypeNil {
+			return &PathError{Path: path.clone(), Err: ErrMixedSequence}
+		}
+	}
+	return nil
+}
+
+// find returns the field at path and the table that contains it, which is
+// nil for a top-level field. If only the last key of path is missing the
+// field is nil; if an earlier one is, find returns an error.
+func (d *Document) find(path Path) (*cstTable, *cstField, error) {
+	var parent *cstTable
+	fields := d.fields
+	for i, k := range path {
+		key := keyOf(k)
+		var f *cstField
+		// Later entries for the same key replace earlier ones
+		for j := len(fields) - 1; j >= 0; j-- {
+			if keyOf(fields[j].key) == key {
+				f = fields[j]
+				break
+			}
+		}
+		switch {
+		case i == len(path)-1:
+			return parent, f, nil
+		case f == nil:
+			return nil, nil, &PathError{Path: path[:i+1].clone(), Err: ErrNotFound}
+		case f.table == nil:
+			return nil, nil, &PathError{Path: path[:i+1].clone(), Err: ErrNotTable, Expected: NodeTypeTable, Actual: f.valueType}
+		}
+		parent = f.table
+		fields = f.table.fields
+	}
+	return parent, nil, nil
+}
+
+// fieldText encodes an entry for path with value n, with lines after the
+// first indented by indent. Entries for values without keys are just the
+// value.
+func (d *Document) fieldText(path Path, n *Node, indent string, positional bool) (string, error) {
+	value, err := d.encode(path, n, indent)
+	if err != nil || positional {
+		return value, err
+	}
+	var b bytes.Buffer
+	e := newEncoder(&b, d.Style)
+	e.key(path[:len(path)-1], path[len(path)-1])
+	e.writeString(e.assign)
+	if err := e.flush(); err != nil {
+		return "", err
+	}
+	return b.String() + value, nil
+}
+
+// encode encodes n in the document's style, with lines after the first
+// indented by indent.
+func (d *Document) encode(path Path, n *Node, indent string) (string, error) {
+	var b bytes.Buffer
+	e := newEncoder(&b, d.Style)
+	e.value(path, n, 0)
+	if err := e.flush(); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(b.String(), "\n", "\n"+indent), nil
+}
+
+// insertBefore adds a value without a key in front of f.
+func (d *Document) insertBefore(f *cstField, path Path, n *Node) error {
+	ls := lineStart(d.src, f.start)
+	if isBlank(d.src[ls:f.start]) {
+		indent := d.src[ls:f.start]
+		text, err := d.fieldText(path, n, indent, true)
+		if err != nil {
+			return err
+		}
+		return d.apply(textEdit{ls, ls, indent + text + ",\n"})
+	}
+	text, err := d.fieldText(path, n, lineIndent(d.src, f.start), true)
+	if err != nil {
+		return err
+	}
+	sep := ", "
+	if d.Style.Compact {
+		sep = ","
+	}
+	return d.apply(textEdit{f.start, f.start, text + sep})
+}
+
+// appendField adds an entry for path's last key to the end of t. The key is
+// left out if the entry is an append to the sequence of values written
+// without keys.
+func (d *Document) appendField(t *cstTable, path Path, n *Node) error {
+	positional := false
+	if i, ok := path[len(path)-1].GetNumber().Int64(); ok && path[len(path)-1].GetType() == NodeTypeNumber {
+		count := 0
+		for _, f := range t.fields {
+			if f.positional {
+				count++
+			}
+		}
+		positional = i == int64(count)+1
+	}
+	trailing := ""
+	if d.Style.TrailingCommas {
+		trailing = ","
+	}
+
+	if len(t.fields) == 0 {
+		ls := lineStart(d.src, t.close)
+		if ls > t.open && isBlank(d.src[ls:t.close]) {
+			indent := d.src[ls:t.close] + d.Style.Indent
+			text, err := d.fieldText(path, n, indent, positional)
+			if err != nil {
+				return err
+			}
+			return d.apply(textEdit{ls, ls, indent + text + trailing + "\n"})
+		}
+		text, err := d.fieldText(path, n, lineIndent(d.src, t.open), positional)
+		if err != nil {
+			return err
+		}
+		return d.apply(textEdit{t.close, t.close, text})
+	}
+
+	last := t.fields[len(t.fields)-1]
+	end := last.valueEnd
+	if last.sep >= 0 {
+		end = last.sep + 1
+	}
+	ls := lineStart(d.src, last.start)
+	le := lineEnd(d.src, end)
+	if isBlank(d.src[ls:last.start]) && isTrivia(d.src[end:le]) && le < len(d.src) {
+		indent := d.src[ls:last.start]
+		text, err := d.fieldText(path, n, indent, positional)
+		if err != nil {
+			return err
+		}
+		edits := []textEdit{{le + 1, le + 1, indent + text + trailing + "\n"}}
+		if last.sep < 0 {
+			edits = append(edits, textEdit{last.valueEnd, last.valueEnd, ","})
+		}
+		return d.apply(edits...)
+	}
+
+	text, err := d.fieldText(path, n, lineIndent(d.src, last.start), positional)
+	if err != nil {
+		return err
+	}
+	sep := ", "
+	if d.Style.Compact {
+		sep = ","
+	}
+	if last.sep >= 0 {
+		return d.apply(textEdit{last.sep + 1, last.sep + 1, sep[1:] + text})
+	}
+	return d.apply(textEdit{last.valueEnd, last.valueEnd, sep + text})
+}
+
+// apply makes non-overlapping edits to the text. Only the entries the edits
+// touch are parsed again, within the innermost table that holds them all;
+// the positions recorded for the rest of the document are moved by the
+// change in length.
+func (d *Document) apply(edits ...textEdit) error {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b strings.Builder
+	pos := 0
+	for _, e := range edits {
+		b.WriteString(d.src[pos:e.start])
+		b.WriteString(e.text)
+		pos = e.end
+	}
+	b.WriteString(d.src[pos:])
+	src := b.String()
+	first, last := edits[0].start, edits[len(edits)-1].end
+	shift := func(offset int) int {
+		moved := offset
+		for _, e := range edits {
+			if e.end <= offset {
+				moved += len(e.text) - (e.end - e.start)
+			}
+		}
+		return moved
+	}
+
+	var t *cstTable
+	fields := d.fields
+	for {
+		i := sort.Search(len(fields), func(i int) bool { return fields[i].start >= last }) - 1
+		if i < 0 || fields[i].table == nil || fields[i].table.open >= first || fields[i].table.close < last {
+			break
+		}
+		t = fields[i].table
+		fields = t.fields
+	}
+
+	// Parse from the last entry that starts before the edits until the
+	// text lines up with an entry that starts after them.
+	p := &cstParser{tz: NewTokenizer(src, nil)}
+	from := sort.Search(len(fields), func(i int) bool { return fields[i].start >= first }) - 1
+	switch {
+	case from >= 0:
+		p.tz.pos = fields[from].start
+	case t != nil:
+		from = 0
+		p.tz.pos = t.open + 1
+	default:
+		from = 0
+	}
+	positional := 0
+	for _, f := range fields[:from] {
+		if f.positional {
+			positional++
+		}
+	}
+	to := sort.Search(len(fields), func(i int) bool { return fields[i].start >= last })
+	var parsed []*cstField
+	for p.next(); p.err == nil; {
+		for to < len(fields) && shift(fields[to].start) < p.tok.Offset {
+			to++
+		}
+		if to < len(fields) && shift(fields[to].start) == p.tok.Offset || t != nil && p.tok.Type == TokenTypeEndTable {
+			break
+		}
+		var f *cstField
+		var err error
+		if t != nil {
+			f, err = p.tableField(&positional)
+		} else {
+			f, err = p.topField()
+		}
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, f)
+	}
+	switch {
+	case p.err == io.EOF && t != nil:
+		return p.tz.errorf(shift(t.open), "table is never closed")
+	case p.err != nil && p.err != io.EOF:
+		return p.err
+	}
+
+	shiftFields(d.fields, first, shift)
+	fields = append(append(fields[:from:from], parsed...), fields[to:]...)
+	d.src = src
+	if t == nil {
+		d.fields = fields
+		return nil
+	}
+	t.fields = fields
+	positional = 0
+	for _, f := range fields {
+		if f.positional {
+			positional++
+			f.key = intKey(positional)
+		}
+	}
+	return nil
+}
+
+// shiftFields moves the offsets recorded in fields and the tables they hold
+// by shift, skipping fields that end before offset first.
+func shiftFields(fields []*cstField, first int, shift func(int) int) {
+	for _, f := range fields {
+		if f.valueEnd < first && f.sep < first {
+			continue
+		}
+		f.start = shift(f.start)
+		f.valueStart = shift(f.valueStart)
+		f.valueEnd = shift(f.valueEnd)
+		if f.sep >= 0 {
+			f.sep = shift(f.sep)
+		}
+		if f.table != nil {
+			f.table.open = shift(f.table.open)
+			f.table.close = shift(f.table.close)
+			shiftFields(f.table.fields, first, shift)
+		}
+	}
+}
+
+// A cstParser builds the syntax tree from tokens by recursive descent.
+// Comments are skipped, so tok is always a significant token.
+type cstParser struct {
+	tz       *Tokenizer
+	tok      Token
+	err      error // io.EOF after the last token
+	bareKeys bool  // Whether any key was written as a bare name
+}
+
+func parseCST(src string) ([]*cstField, bool, error) {
+	p := &cstParser{tz: NewTokenizer(src, nil)}
+	var fields []*cstField
+	for p.next(); p.err == nil; {
+		f, err := p.topField()
+		if err != nil {
+			return nil, false, err
+		}
+		fields = append(fields, f)
+	}
+	if p.err != io.EOF {
+		return nil, false, p.err
+	}
+	return fields, p.bareKeys, nil
+}
+
+// topField parses a top-level assignment starting at the current token.
+func (p *cstParser) topField() (*cstField, error) {
+	if p.tok.Type != TokenTypeIdentifier || isKeyword(p.tok.Value) {
+		return nil, p.errorf("expected a variable name")
+	}
+	f := &cstField{start: p.tok.Offset, key: NewNode(NodeTypeString, p.tok.Value), sep: -1}
+	if err := p.expectNext(TokenTypeEquals, "="); err != nil {
+		return nil, err
+	}
+	if err := p.value(f); err != nil {
+		return nil, err
+	}
+	if p.err == nil && p.tok.Type == TokenTypeComma {
+		f.sep = p.tok.Offset
+		p.next()
+	}
+	return f, nil
+}
+
+func (p *cstParser) next() {
+	for {
+		p.tok, p.err = p.tz.Next()
+		if p.err != nil || p.tok.Type != TokenTypeIgnore {
+			return
+		}
+	}
+}
+
+// expectNext moves past the current token, which must be followed by one
+// of type tType, written as text, and then past that one too.
+func (p *cstParser) expectNext(tType int, text string) error {
+	p.next()
+	return p.expect(tType, text)
+}
+
+// expect moves past the current token, which must be of type tType.
+func (p *cstParser) expect(tType int, text string) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	if p.tok.Type != tType {
+		return p.errorf("expected '%s'", text)
+	}
+	p.next()
+	return nil
+}
+
+// check returns an error if there's no current token.
+func (p *cstParser) check() error {
+	if p.err == io.EOF {
+		return p.tz.errorf(len(p.tz.src), "unexpected end of input")
+	}
+	return p.err
+}
+
+func (p *cstParser) errorf(tmpl string, v ...interface{}) error {
+	if p.err != nil {
+		return p.check()
+	}
+	return p.tz.errorf(p.tok.Offset, tmpl, v...)
+}
+
+// value parses the value of f starting at the current token.
+func (p *cstParser) value(f *cstField) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	f.valueStart = p.tok.Offset
+	if p.tok.Type == TokenTypeStartTable {
+		t, err := p.table()
+		if err != nil {
+			return err
+		}
+		f.table = t
+		f.valueType = NodeTypeTable
+		f.valueEnd = t.close + 1
+		return nil
+	}
+	n, err := p.scalar()
+	if err != nil {
+		return err
+	}
+	f.valueType = n.GetType()
+	f.valueEnd = p.tok.End
+	p.next()
+	return nil
+}
+
+// scalar returns the node for the current token, which must be a string,
+// number, boolean or nil.
+func (p *cstParser) scalar() (*Node, error) {
+	switch p.tok.Type {
+	case TokenTypeString, TokenTypeNumber:
+	case TokenTypeIdentifier:
+		if !isKeyword(p.tok.Value) {
+			return nil, p.errorf("unexpected name %q", p.tok.Value)
+		}
+	default:
+		return nil, p.errorf("expected a value")
+	}
+	return tokenToNode(&p.tok)
+}
+
+func (p *cstParser) table() (*cstTable, error) {
+	t := &cstTable{open: p.tok.Offset}
+	positional := 0
+	for p.next(); p.err == nil && p.tok.Type != TokenTypeEndTable; {
+		f, err := p.tableField(&positional)
+		if err != nil {
+			return nil, err
+		}
+		t.fields = append(t.fields, f)
+	}
+	if p.err == io.EOF {
+		return nil, p.tz.errorf(t.open, "table is never closed")
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	t.close = p.tok.Offset
+	p.next()
+	return t, nil
+}
+
+// tableField parses an entry in a table constructor starting at the current
+// token. positional counts the values written without keys so far.
+func (p *cstParser) tableField(positional *int) (*cstField, error) {
+	f := &cstField{start: p.tok.Offset, sep: -1}
+	switch {
+	case p.tok.Type == TokenTypeStartKey:
+		p.next()
+		if err := p.check(); err != nil {
+			return nil, err
+		}
+		k, err := p.scalar()
+		if err != nil {
+			return nil, err
+		}
+		if k.IsNil() {
+			return nil, p.errorf("table key is nil")
+		}
+		f.key = k
+		if err := p.expectNext(TokenTypeEndKey, "]"); err != nil {
+			return nil, err
+		}
+		if err := p.expect(TokenTypeEquals, "="); err != nil {
+			return nil, err
+		}
+	case p.tok.Type == TokenTypeIdentifier && !isKeyword(p.tok.Value):
+		f.key = NewNode(NodeTypeString, p.tok.Value)
+		p.bareKeys = true
+		if err := p.expectNext(TokenTypeEquals, "="); err != nil {
+			return nil, err
+		}
+	default:
+		*positional++
+		f.key = intKey(*positional)
+		f.positional = true
+	}
+	if err := p.value(f); err != nil {
+		return nil, err
+	}
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	switch p.tok.Type {
+	case TokenTypeComma:
+		f.sep = p.tok.Offset
+		p.next()
+	case TokenTypeEndTable:
+	default:
+		return nil, p.errorf("expected ',' or '}'")
+	}
+	return f, nil
+}
+
+// detectIndent returns the leading whitespace of the first indented line,
+// or a tab if there isn't one.
+func detectIndent(src string) string {
+	for i := strings.IndexByte(src, '\n'); i >= 0 && i+1 < len(src); {
+		line := src[i+1:]
+		if indent := lineIndent(line, 0); indent != "" && !isBlank(line[:lineEnd(line, 0)]) {
+			return indent
+		}
+		next := strings.IndexByte(line, '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "\t"
+}
+
+// lineStart returns the offset of the start of the line containing offset.
+func lineStart(src string, offset int) int {
+	return strings.LastIndexByte(src[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset of the newline ending the line containing
+// offset, or the length of src if it's the last line.
+func lineEnd(src string, offset int) int {
+	if i := strings.IndexByte(src[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(src)
+}
+
+// lineIndent returns the leading whitespace of the line containing offset.
+func lineIndent(src string, offset int) string {
+	line := src[lineStart(src, offset):]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+func isBlank(s string) bool {
+	return strings.TrimLeft(s, " \t\r") == ""
+}
+
+// isTrivia returns whether the rest of a line holds only whitespace and a
+// comment that ends on that line.
+func isTrivia(s string) bool {
+	s = strings.TrimLeft(s, " \t\r")
+	if s == "" {
+		return true
+	}
+	return strings.HasPrefix(s, "--") && longBracketLevel(s[2:]) < 0
+}
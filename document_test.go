@@ -0,0 +1,196 @@
+package wowlua
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const hand_config = `-- Settings for the raid roster
+Config = {
+    scale = 0x10, -- hex on purpose
+    ["name"] = 'Volne',
+
+    alts = { "Ghañk", "Tesk" },
+    ranks = {
+        "Officer", -- [1]
+        "Raider", -- [2]
+    },
+    empty = {
+    },
+}
+Version = 1.50
+`
+
+func TestDocumentUnedited(t *testing.T) {
+	doc, err := ParseDocument(hand_config)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %v", err)
+	}
+	if doc.String() != hand_config {
+		t.Errorf("Expected unedited document to be unchanged, got:\n%s", doc)
+	}
+	tab, err := doc.Table()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document as a table: %v", err)
+	}
+	if name, _ := tab.AsString(NewPath("Config", "name")...); name != "Volne" {
+		t.Errorf("Expected name Volne, got %q", name)
+	}
+}
+
+func TestDocumentEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(*Document) error
+		expected string
+	}{
+		{"set scalar", func(d *Document) error {
+			return d.SetValue(NewPath("Config", "name"), NewNode(NodeTypeString, "Tesk"))
+		}, `    ["name"] = "Tesk",`},
+		{"set table", func(d *Document) error {
+			return d.SetValue(NewPath("Config", "scale"), NodeOf(Map("x", 1)))
+		}, "    scale = {\n        x = 1,\n    }, -- hex on purpose"},
+		{"add key", func(d *Document) error {
+			return d.SetValue(NewPath("Config", "ranks", "count"), NodeOf(Int(2)))
+		}, "        \"Raider\", -- [2]\n        count = 2,\n    },"},
+		{"add to empty", func(d *Document) error {
+			return d.SetValue(NewPath("Config", "empty", "x"), NodeOf(Bool(true)))
+		}, "    empty = {\n        x = true,\n    },"},
+		{"add top level", func(d *Document) error {
+			return d.SetValue(NewPath("Debug"), NodeOf(Bool(false)))
+		}, "Version = 1.50\nDebug = false\n"},
+		{"delete line", func(d *Document) error {
+			return d.Delete(NewPath("Config", "scale"))
+		}, "Config = {\n    [\"name\"] = 'Volne',"},
+		{"delete inline", func(d *Document) error {
+			return d.Delete(NewPath("Config", "alts", 2))
+		}, `    alts = { "Ghañk" },`},
+		{"delete with nil", func(d *Document) error {
+			return d.SetValue(NewPath("Config", "name"), nil)
+		}, "Config = {\n    scale = 0x10, -- hex on purpose\n\n    alts"},
+		{"positional nil", func(d *Document) error {
+			return d.SetValue(NewPath("Config", "alts", 1), nil)
+		}, `    alts = { nil, "Tesk" },`},
+		{"insert line", func(d *Document) error {
+			return d.Insert(NewPath("Config", "ranks", 2), NewNode(NodeTypeString, "Member"))
+		}, "        \"Officer\", -- [1]\n        \"Member\",\n        \"Raider\", -- [2]"},
+		{"insert inline", func(d *Document) error {
+			return d.Insert(NewPath("Config", "alts", 3), NewNode(NodeTypeString, "Volne"))
+		}, `    alts = { "Ghañk", "Tesk", "Volne" },`},
+	}
+	for _, test := range tests {
+		doc, err := ParseDocument(hand_config)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing document: %v", err)
+		}
+		if err := test.edit(doc); err != nil {
+			t.Errorf("Unexpected error for %s: %v", test.name, err)
+			continue
+		}
+		if !strings.Contains(doc.String(), test.expected) {
+			t.Errorf("Expected %s to produce %q, got:\n%s", test.name, test.expected, doc)
+		}
+		if _, err := doc.Table(); err != nil {
+			t.Errorf("Unexpected error parsing document after %s: %v", test.name, err)
+		}
+	}
+}
+
+func TestDocumentEditSpans(t *testing.T) {
+	doc, err := ParseDocument(hand_config)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %v", err)
+	}
+	edits := []func() error{
+		func() error { return doc.SetValue(NewPath("Config", "scale"), NodeOf(Map("x", Seq(1, 2)))) },
+		func() error {
+			return doc.Insert(NewPath("Config", "ranks", 1), NewNode(NodeTypeString, "Guild Master"))
+		},
+		func() error { return doc.SetValue(NewPath("Config", "scale", "x", 3), NodeOf(Int(3))) },
+		func() error { return doc.Delete(NewPath("Config", "alts", 1)) },
+		func() error { return doc.SetValue(NewPath("Debug"), NodeOf(Map())) },
+		func() error { return doc.SetValue(NewPath("Debug", "on"), NodeOf(Bool(true))) },
+		func() error { return doc.SetValue(NewPath("Config", "ranks", 2), nil) },
+		func() error { return doc.Delete(NewPath("Version")) },
+		func() error { return doc.SetValue(NewPath("Config", "empty", 1), NewNode(NodeTypeString, "x")) },
+	}
+	for i, edit := range edits {
+		if err := edit(); err != nil {
+			t.Fatalf("Unexpected error in edit %d: %v", i, err)
+		}
+		fields, _, err := parseCST(doc.String())
+		if err != nil {
+			t.Fatalf("Unexpected error parsing after edit %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(doc.fields, fields) {
+			t.Errorf("Expected the syntax tree after edit %d to match a fresh parse of:\n%s", i, doc)
+		}
+	}
+}
+
+func TestDocumentMixedSequence(t *testing.T) {
+	doc, err := ParseDocument("DB = {\n    list = { \"x\", \"y\", \"z\" },\n}\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %v", err)
+	}
+	list := NewPath("DB", "list")
+	if err := doc.Delete(append(list, NodeOf(Int(3)))); err != nil {
+		t.Fatalf("Unexpected error deleting [3]: %v", err)
+	}
+	if err := doc.SetValue(append(list, NodeOf(Int(4))), NodeOf(Int(34))); err != nil {
+		t.Fatalf("Unexpected error setting [4]: %v", err)
+	}
+	if err := doc.SetValue(append(list, NodeOf(Int(3))), NodeOf(Int(73))); err != nil {
+		t.Fatalf("Unexpected error setting [3]: %v", err)
+	}
+	before := doc.String()
+	if err := doc.Insert(append(list, NodeOf(Int(3))), NodeOf(Int(60))); !errors.Is(err, ErrMixedSequence) {
+		t.Errorf("Expected ErrMixedSequence inserting before a keyed entry, got %v", err)
+	}
+	if err := doc.Delete(append(list, NodeOf(Int(1)))); !errors.Is(err, ErrMixedSequence) {
+		t.Errorf("Expected ErrMixedSequence deleting before a keyed entry, got %v", err)
+	}
+	if doc.String() != before {
+		t.Errorf("Expected failed edits to leave the document unchanged, got:\n%s", doc)
+	}
+	tab, err := doc.Table()
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document as a table: %v", err)
+	}
+	expected := Seq("x", "y", 73, 34)
+	if got := tab.GetByString("DB").GetTable().GetByString("list").GetTable(); !got.Equals(expected) {
+		t.Errorf("Expected list %v, got %v", expected, got)
+	}
+
+	doc, err = ParseDocument("L = { 1, nil, 3 }\n")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %v", err)
+	}
+	if err := doc.Insert(NewPath("L", 1), NodeOf(Int(0))); !errors.Is(err, ErrMixedSequence) {
+		t.Errorf("Expected ErrMixedSequence inserting before a hole, got %v", err)
+	}
+	if err := doc.Insert(NewPath("L", 4), NodeOf(Int(4))); err != nil {
+		t.Errorf("Unexpected error appending after a hole: %v", err)
+	}
+}
+
+func TestDocumentEditErrors(t *testing.T) {
+	doc, err := ParseDocument(hand_config)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing document: %v", err)
+	}
+	if err := doc.SetValue(NewPath("Config", "missing", "x"), NodeOf(Int(1))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound setting under a missing table, got %v", err)
+	}
+	if err := doc.Delete(NewPath("Config", "scale", "x")); !errors.Is(err, ErrNotTable) {
+		t.Errorf("Expected ErrNotTable deleting under a number, got %v", err)
+	}
+	if err := doc.Insert(NewPath("Config", "alts", 4), NodeOf(Int(1))); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected ErrOutOfRange inserting past the end, got %v", err)
+	}
+	if doc.String() != hand_config {
+		t.Errorf("Expected failed edits to leave the document unchanged, got:\n%s", doc)
+	}
+}
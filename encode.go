@@ -0,0 +1,497 @@
+package wowlua
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrCycle indicates a table contains itself and can't be encoded
+	ErrCycle = errors.New("table contains itself")
+	// ErrNotName indicates a top-level key that isn't a valid Lua name
+	ErrNotName = errors.New("key is not a valid Lua name")
+)
+
+// EncoderOptions controls the layout of encoded Lua. The zero value writes
+// every table on multiple lines with no indentation; most callers will want
+// one of the predefined styles, possibly with a few fields changed.
+type EncoderOptions struct {
+	// Indent is written once per nesting level at the start of each line.
+	Indent string
+	// Compact writes everything on one line with no optional spaces. Indent,
+	// InlineThreshold, Width, TrailingCommas and IndexComments are ignored.
+	Compact bool
+	// SortKeys writes keys in canonical order instead of the order they were
+	// added: the sequence part first, followed by other numeric keys in
+	// ascending order, then string keys in byte order, then false and true.
+	SortKeys bool
+	// InlineThreshold is the largest number of entries a table may have to
+	// be written on one line, as in {1, 2, 3}. Nested tables must also be
+	// within the threshold. Zero never writes tables on one line.
+	InlineThreshold int
+	// Width, if positive, is the longest line, in bytes, that a table will be
+	// written on one line to produce.
+	Width int
+	// BareKeys writes string keys that are valid Lua names as name = value
+	// instead of ["name"] = value.
+	BareKeys bool
+	// TrailingCommas writes a comma after the last entry of tables written on
+	// multiple lines.
+	TrailingCommas bool
+	// IndexComments follows each value in the sequence part of a table
+	// written on multiple lines with a comment giving its index.
+	IndexComments bool
+	// ExactFloats writes floats in the shortest form that reads back exactly
+	// instead of like C's %.14g. Floats with integer values get a ".0", as
+	// in 1.0, so they read back as floats rather than integers.
+	ExactFloats bool
+}
+
+var (
+	// WoWStyle mirrors the files WoW writes: tables are indented with tabs,
+	// each entry is on its own line, keys are written as ["key"] or [n], the
+	// sequence part of a table comes first with its values followed by
+	// `-- [n]` comments, integers are written exactly and floats are
+	// formatted like C's %.14g.
+	WoWStyle = EncoderOptions{
+		Indent:         "\t",
+		TrailingCommas: true,
+		IndexComments:  true,
+	}
+	// CanonicalStyle is WoWStyle with sorted keys and exact floats, so the
+	// output depends only on the data and not the order entries were added
+	// or parsed in.
+	CanonicalStyle = EncoderOptions{
+		Indent:         "\t",
+		SortKeys:       true,
+		TrailingCommas: true,
+		IndexComments:  true,
+		ExactFloats:    true,
+	}
+	// CompactStyle writes a single line as short as possible, for embedding
+	// in export strings.
+	CompactStyle = EncoderOptions{
+		Compact:     true,
+		BareKeys:    true,
+		ExactFloats: true,
+	}
+	// PrettyStyle is meant for people to read: short tables go on one line,
+	// lines are kept within 80 bytes where possible and keys are bare.
+	PrettyStyle = EncoderOptions{
+		Indent:          "  ",
+		InlineThreshold: 8,
+		Width:           80,
+		BareKeys:        true,
+		TrailingCommas:  true,
+		ExactFloats:     true,
+	}
+)
+
+// Encode writes doc as SavedVariables text in WoWStyle: one `Name = value`
+// assignment per entry, as returned by ParseLua. Every key of doc must be a
+// string that is a valid Lua name.
+func Encode(w io.Writer, doc *Table) error {
+	return WoWStyle.Encode(w, doc)
+}
+
+// EncodeCanonical is like Encode but writes in CanonicalStyle, so files
+// written by different WoW sessions can be compared with diff. Top-level
+// assignments are sorted by name.
+func EncodeCanonical(w io.Writer, doc *Table) error {
+	return CanonicalStyle.Encode(w, doc)
+}
+
+// EncodeLua returns the table encoded as a Lua table constructor in
+// WoWStyle.
+func (t *Table) EncodeLua() ([]byte, error) {
+	return WoWStyle.Marshal(t)
+}
+
+// EncodeCanonicalLua returns the table encoded as a Lua table constructor in
+// CanonicalStyle.
+func (t *Table) EncodeCanonicalLua() ([]byte, error) {
+	return CanonicalStyle.Marshal(t)
+}
+
+// Encode writes doc as one `Name = value` assignment per entry, each on its
+// own line. Every key of doc must be a string that is a valid Lua name.
+func (o EncoderOptions) Encode(w io.Writer, doc *Table) error {
+	e := newEncoder(w, o)
+	for _, entry := range e.entries(doc, 0) {
+		name := entry.key.GetString()
+		if entry.key.GetType() != NodeTypeString || !IsName(name) {
+			return &PathError{Path: Path{entry.key}, Err: ErrNotName}
+		}
+		e.writeString(name)
+		e.writeString(e.assign)
+		e.value(Path{entry.key}, entry.value, 0)
+		e.writeByte('\n')
+	}
+	return e.flush()
+}
+
+// Marshal returns the table encoded as a Lua table constructor.
+func (o EncoderOptions) Marshal(t *Table) ([]byte, error) {
+	var b bytes.Buffer
+	e := newEncoder(&b, o)
+	e.table(nil, t, 0)
+	if err := e.flush(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+type encoder struct {
+	w       *bufio.Writer
+	opts    EncoderOptions
+	err     error
+	scratch []byte
+	active  map[*Table]bool // Tables being encoded, to detect cycles
+	col     int             // Bytes written since the last newline
+	inline  bool            // Writing a table on one line, so nested tables are too
+	sep     string          // Between entries of a table on one line
+	assign  string          // Between a key and its value
+}
+
+func newEncoder(w io.Writer, opts EncoderOptions) *encoder {
+	e := &encoder{
+		w:      bufio.NewWriter(w),
+		opts:   opts,
+		active: make(map[*Table]bool),
+		sep:    ", ",
+		assign: " = ",
+	}
+	if opts.Compact {
+		e.inline = true
+		e.sep = ","
+		e.assign = "="
+	}
+	return e
+}
+
+func (e *encoder) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func (e *encoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *encoder) writeString(s string) {
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+		if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+			e.col = len(s) - i - 1
+		} else {
+			e.col += len(s)
+		}
+	}
+}
+
+func (e *encoder) writeByte(c byte) {
+	if e.err == nil {
+		e.err = e.w.WriteByte(c)
+		if c == '\n' {
+			e.col = 0
+		} else {
+			e.col++
+		}
+	}
+}
+
+func (e *encoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+		if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+			e.col = len(b) - i - 1
+		} else {
+			e.col += len(b)
+		}
+	}
+}
+
+func (e *encoder) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		e.writeString(e.opts.Indent)
+	}
+}
+
+// value writes a node as a Lua expression. Tables start on the current line
+// and their entries are indented one level deeper than depth.
+func (e *encoder) value(path Path, n *Node, depth int) {
+	switch n.GetType() {
+	case NodeTypeString, NodeTypeIdentifier:
+		e.scratch = appendQuotedLua(e.scratch[:0], n.GetString())
+		e.write(e.scratch)
+	case NodeTypeNumber:
+		s, err := formatNumber(n.GetNumber(), e.opts.ExactFloats)
+		if err != nil {
+			e.fail(&PathError{Path: path.clone(), Err: err})
+			return
+		}
+		e.writeString(s)
+	case NodeTypeBool:
+		e.writeString(strconv.FormatBool(n.GetBool()))
+	case NodeTypeNil:
+		e.writeString("nil")
+	case NodeTypeTable:
+		e.table(path, n.GetTable(), depth)
+	default:
+		e.fail(&PathError{Path: path.clone(), Err: fmt.Errorf("can't encode %v", n.GetType())})
+	}
+}
+
+func (e *encoder) table(path Path, t *Table, depth int) {
+	if e.active[t] {
+		e.fail(&PathError{Path: path.clone(), Err: ErrCycle})
+		return
+	}
+	e.active[t] = true
+	defer delete(e.active, t)
+
+	switch {
+	case e.inline:
+		e.inlineTable(path, t)
+	case e.opts.InlineThreshold > 0 && e.inlineable(t) && e.tryInline(path, t):
+	default:
+		e.multilineTable(path, t, depth)
+	}
+}
+
+// tryInline writes t on one line if that keeps the line within the width
+// limit, leaving room for a following comma, and returns whether it did.
+func (e *encoder) tryInline(path Path, t *Table) bool {
+	var b bytes.Buffer
+	sub := newEncoder(&b, e.opts)
+	sub.inline = true
+	sub.active = e.active
+	sub.inlineTable(path, t)
+	if err := sub.flush(); err != nil {
+		e.fail(err)
+		return true
+	}
+	if e.opts.Width > 0 && e.col+b.Len()+1 > e.opts.Width {
+		return false
+	}
+	e.write(b.Bytes())
+	return true
+}
+
+// inlineable returns whether t and every table nested in it are within the
+// inline threshold. Tables that contain themselves are not.
+func (e *encoder) inlineable(t *Table) bool {
+	if t.Len() > e.opts.InlineThreshold {
+		return false
+	}
+	for _, entry := range t.entries {
+		if entry.value.GetType() != NodeTypeTable {
+			continue
+		}
+		sub := entry.value.GetTable()
+		if e.active[sub] {
+			return false
+		}
+		e.active[sub] = true
+		ok := e.inlineable(sub)
+		delete(e.active, sub)
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *encoder) inlineTable(path Path, t *Table) {
+	e.writeByte('{')
+	seqLen := t.SeqLen()
+	for i := 1; i <= seqLen; i++ {
+		if i > 1 {
+			e.writeString(e.sep)
+		}
+		k := intKey(i)
+		e.value(path.child(k), t.Get(k), 0)
+	}
+	for i, entry := range e.entries(t, seqLen) {
+		if i > 0 || seqLen > 0 {
+			e.writeString(e.sep)
+		}
+		e.key(path, entry.key)
+		e.writeString(e.assign)
+		e.value(path.child(entry.key), entry.value, 0)
+	}
+	e.writeByte('}')
+}
+
+func (e *encoder) multilineTable(path Path, t *Table, depth int) {
+	e.writeString("{\n")
+	seqLen := t.SeqLen()
+	entries := e.entries(t, seqLen)
+	last := seqLen + len(entries)
+	for i := 1; i <= seqLen; i++ {
+		k := intKey(i)
+		e.indent(depth + 1)
+		e.value(path.child(k), t.Get(k), depth+1)
+		e.comma(i == last)
+		if e.opts.IndexComments {
+			e.writeString(" -- [")
+			e.writeString(strconv.Itoa(i))
+			e.writeByte(']')
+		}
+		e.writeByte('\n')
+	}
+	for i, entry := range entries {
+		e.indent(depth + 1)
+		e.key(path, entry.key)
+		e.writeString(e.assign)
+		e.value(path.child(entry.key), entry.value, depth+1)
+		e.comma(seqLen+i+1 == last)
+		e.writeByte('\n')
+	}
+	e.indent(depth)
+	e.writeByte('}')
+}
+
+// comma writes the separator after an entry of a table on multiple lines.
+func (e *encoder) comma(last bool) {
+	if !last || e.opts.TrailingCommas {
+		e.writeByte(',')
+	}
+}
+
+// entries returns the entries of t outside its sequence part in the order
+// they should be written.
+func (e *encoder) entries(t *Table, seqLen int) []*tableEntry {
+	entries := make([]*tableEntry, 0, len(t.entries)-seqLen)
+	for _, entry := range t.entries {
+		if !isSeqKey(entry.key, seqLen) {
+			entries = append(entries, entry)
+		}
+	}
+	if e.opts.SortKeys {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return canonicalLess(entries[i].key, entries[j].key)
+		})
+	}
+	return entries
+}
+
+// canonicalKeyRank orders key types: numbers, then strings, then bools,
+// then tables, which keep their order.
+var canonicalKeyRank = map[NodeType]int{
+	NodeTypeNumber:     0,
+	NodeTypeString:     1,
+	NodeTypeIdentifier: 1,
+	NodeTypeBool:       2,
+	NodeTypeTable:      3,
+}
+
+func canonicalLess(a, b *Node) bool {
+	ra, rb := canonicalKeyRank[a.GetType()], canonicalKeyRank[b.GetType()]
+	if ra != rb {
+		return ra < rb
+	}
+	switch a.GetType() {
+	case NodeTypeNumber:
+		return a.GetNumber().Less(b.GetNumber())
+	case NodeTypeString, NodeTypeIdentifier:
+		return a.GetString() < b.GetString()
+	case NodeTypeBool:
+		return !a.GetBool() && b.GetBool()
+	}
+	return false
+}
+
+// key writes a table key in brackets, or bare if allowed.
+func (e *encoder) key(path Path, k *Node) {
+	switch k.GetType() {
+	case NodeTypeTable:
+		e.fail(&PathError{Path: path.child(k), Err: errors.New("can't encode a table as a key")})
+		return
+	case NodeTypeString, NodeTypeIdentifier:
+		if e.opts.BareKeys && IsName(k.GetString()) {
+			e.writeString(k.GetString())
+			return
+		}
+	}
+	e.writeByte('[')
+	e.value(path.child(k), k, 0)
+	e.writeByte(']')
+}
+
+// isSeqKey returns whether k is an integer key within the sequence part of a
+// table with sequence length seqLen.
+func isSeqKey(k *Node, seqLen int) bool {
+	if k.GetType() != NodeTypeNumber {
+		return false
+	}
+	i, ok := k.GetNumber().Int64()
+	return ok && i >= 1 && i <= int64(seqLen)
+}
+
+// formatNumber formats integers exactly and floats like C's %.14g, as WoW
+// does, or if exact in the shortest form that reads back as the same float.
+// Infinities are written as numbers too large to represent, which Lua reads
+// back as infinity. NaN has no literal form and is an error.
+func formatNumber(n Number, exact bool) (string, error) {
+	if n.IsInteger() {
+		i, _ := n.Int64()
+		return strconv.FormatInt(i, 10), nil
+	}
+	f := n.Float64()
+	switch {
+	case math.IsNaN(f):
+		return "", errors.New("can't encode NaN")
+	case math.IsInf(f, 1):
+		return "1e9999", nil
+	case math.IsInf(f, -1):
+		return "-1e9999", nil
+	}
+	if exact {
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	}
+	return strconv.FormatFloat(f, 'g', 14, 64), nil
+}
+
+// appendQuotedLua appends s to b as a double-quoted Lua string. Quotes,
+// backslashes and control characters are escaped; other bytes, including
+// UTF-8 sequences, are written as they are.
+func appendQuotedLua(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if c < ' ' || c == 0x7f {
+				// Always three digits so a following digit isn't absorbed
+				b = append(b, '\\', '0'+c/100, '0'+c/10%10, '0'+c%10)
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,182 @@
+package wowlua
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodeMatchesWoW(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	var b bytes.Buffer
+	if err := Encode(&b, tab); err != nil {
+		t.Fatalf("Unexpected error encoding: %v", err)
+	}
+	expected := strings.TrimPrefix(sample_data, "\n")
+	if b.String() != expected {
+		t.Errorf("Expected encoding to match the WoW-written sample, got:\n%s", b.String())
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tab := Map(
+		"Strings", Seq("quote \" backslash \\", "line\nbreak", "tab\tbell\a7", "Ghañk"),
+		"Numbers", Map("int", Int(9007199254740993), "float", Num(0.1), "big", Num(1e20), "neg", Int(-5)),
+		"Flags", Map(true, "yes", 3, false),
+		"Empty", NewTable(),
+	)
+	var b bytes.Buffer
+	if err := Encode(&b, tab); err != nil {
+		t.Fatalf("Unexpected error encoding: %v", err)
+	}
+	parsed, err := ParseLua(b.String())
+	if err != nil {
+		t.Fatalf("Unexpected error parsing encoded data: %v\n%s", err, b.String())
+	}
+	if !parsed.Equals(tab) {
+		t.Errorf("Expected encoded data to parse back the same, got:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), "Empty = {\n}\n") {
+		t.Errorf("Expected empty tables in WoW style, got:\n%s", b.String())
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, Map("not a name", 1)); !errors.Is(err, ErrNotName) {
+		t.Errorf("Expected ErrNotName, got %v", err)
+	}
+	loop := NewTable()
+	loop.Set(NewNode(NodeTypeString, "self"), NodeOf(loop))
+	if _, err := loop.EncodeLua(); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle, got %v", err)
+	}
+}
+
+func TestEncodeCanonical(t *testing.T) {
+	first := "B = {\n\t[true] = 1,\n\t[\"z\"] = 0.1,\n\t[10] = \"ten\",\n\t[1] = \"one\",\n\t[\"a\"] = 1.50,\n\t[false] = 0,\n\t[-2] = 0x10,\n}\nA = \"\\65\"\n"
+	second := "A = 'A'\nB = {\n\t\"one\",\n\t[false] = 0,\n\t[\"a\"] = 1.5,\n\t[-2] = 16,\n\t[true] = 1,\n\t[10] = \"ten\",\n\t[\"z\"] = 0.1,\n}\n"
+	expected := "A = \"A\"\nB = {\n\t\"one\", -- [1]\n\t[-2] = 16,\n\t[10] = \"ten\",\n\t[\"a\"] = 1.5,\n\t[\"z\"] = 0.1,\n\t[false] = 0,\n\t[true] = 1,\n}\n"
+	for _, input := range []string{first, second, expected} {
+		tab, err := ParseLua(input)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", input, err)
+		}
+		var b bytes.Buffer
+		if err := EncodeCanonical(&b, tab); err != nil {
+			t.Fatalf("Unexpected error encoding: %v", err)
+		}
+		if b.String() != expected {
+			t.Errorf("Expected canonical encoding:\n%s\ngot:\n%s", expected, b.String())
+		}
+	}
+}
+
+func TestEncodeCanonicalLargeIntegerKeys(t *testing.T) {
+	expected := "X = {\n\t[-1.5] = 0,\n\t[9007199254740992] = 2,\n\t[9007199254740993] = 1,\n\t[9.007199254740994e+15] = 3,\n}\n"
+	for _, input := range []string{
+		"X = { [9007199254740993] = 1, [9007199254740992] = 2, [9007199254740994.5] = 3, [-1.5] = 0 }",
+		"X = { [-1.5] = 0, [9007199254740994.5] = 3, [9007199254740992] = 2, [9007199254740993] = 1 }",
+	} {
+		tab, err := ParseLua(input)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", input, err)
+		}
+		var b bytes.Buffer
+		if err := EncodeCanonical(&b, tab); err != nil {
+			t.Fatalf("Unexpected error encoding: %v", err)
+		}
+		if b.String() != expected {
+			t.Errorf("Expected canonical encoding:\n%s\ngot:\n%s", expected, b.String())
+		}
+	}
+}
+
+func TestEncodeCanonicalFloats(t *testing.T) {
+	tab, err := ParseLua("X = { 1.0, 2.5, 1e20, -0.0, 3 }")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing: %v", err)
+	}
+	var b bytes.Buffer
+	if err := EncodeCanonical(&b, tab); err != nil {
+		t.Fatalf("Unexpected error encoding: %v", err)
+	}
+	expected := "X = {\n\t1.0, -- [1]\n\t2.5, -- [2]\n\t1e+20, -- [3]\n\t-0.0, -- [4]\n\t3, -- [5]\n}\n"
+	if b.String() != expected {
+		t.Errorf("Expected canonical encoding:\n%s\ngot:\n%s", expected, b.String())
+	}
+	back, err := ParseLua(b.String())
+	if err != nil {
+		t.Fatalf("Unexpected error parsing encoding: %v", err)
+	}
+	x := back.GetByString("X").GetTable()
+	for i := 1; i <= 5; i++ {
+		if n := x.GetIndexed(i); n.IsInteger() != (i == 5) {
+			t.Errorf("Expected value %d to keep its integer or float type, got %v", i, n)
+		}
+	}
+}
+
+func TestEncoderOptions(t *testing.T) {
+	tab := Map(
+		"pos", Seq(1, 2, 3),
+		"name", "Volne",
+		"long", Seq("aaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbbbbbbbb", "cccccccccccccccccccccccc"),
+		"nested", Map("x", Seq(1.5, 2), 4, true),
+		"my key", NewTable(),
+	)
+	tests := []struct {
+		name     string
+		opts     EncoderOptions
+		table    *Table
+		expected string
+	}{
+		{"compact", CompactStyle, tab, `{pos={1,2,3},name="Volne",long={"aaaaaaaaaaaaaaaaaaaa","bbbbbbbbbbbbbbbbbbbbbbbbbb","cccccccccccccccccccccccc"},nested={x={1.5,2},[4]=true},["my key"]={}}`},
+		{"pretty", PrettyStyle, tab, `{
+  pos = {1, 2, 3},
+  name = "Volne",
+  long = {
+    "aaaaaaaaaaaaaaaaaaaa",
+    "bbbbbbbbbbbbbbbbbbbbbbbbbb",
+    "cccccccccccccccccccccccc",
+  },
+  nested = {x = {1.5, 2}, [4] = true},
+  ["my key"] = {},
+}`},
+		{"no trailing commas", EncoderOptions{Indent: " ", SortKeys: true, InlineThreshold: 1}, tab.GetByString("nested").GetTable(), `{
+ [4] = true,
+ ["x"] = {
+  1.5,
+  2
+ }
+}`},
+	}
+	for _, test := range tests {
+		b, err := test.opts.Marshal(test.table)
+		if err != nil {
+			t.Fatalf("Unexpected error encoding %s: %v", test.name, err)
+		}
+		if string(b) != test.expected {
+			t.Errorf("Expected %s encoding:\n%s\ngot:\n%s", test.name, test.expected, b)
+		}
+		parsed, err := ParseLua("T = " + string(b))
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s encoding: %v", test.name, err)
+		}
+		if !parsed.GetByString("T").GetTable().Equals(test.table) {
+			t.Errorf("Expected %s encoding to parse back to the same table", test.name)
+		}
+	}
+}
+
+func TestCanonicalLessTableKeys(t *testing.T) {
+	table_key := NewNode(NodeTypeTable, Map())
+	for _, k := range []*Node{NodeOf(Int(1)), NewNode(NodeTypeString, "a"), NodeOf(Bool(true))} {
+		if canonicalLess(table_key, k) || !canonicalLess(k, table_key) {
+			t.Errorf("Expected table keys to sort after %v", k)
+		}
+	}
+}
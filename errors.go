@@ -0,0 +1,54 @@
+package wowlua
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PathError records an error and the path of the node that caused it. For
+// ErrWrongType and ErrNotTable, Expected and Actual give the type that was
+// wanted and the type that was found.
+type PathError struct {
+	Path     Path
+	Err      error
+	Expected NodeType
+	Actual   NodeType
+}
+
+func (e *PathError) Error() string {
+	msg := e.Err.Error()
+	if errors.Is(e.Err, ErrWrongType) || errors.Is(e.Err, ErrNotTable) {
+		msg = fmt.Sprintf("%v: expected %v, got %v", msg, e.Expected, e.Actual)
+	}
+	if len(e.Path) == 0 {
+		return msg
+	}
+	return e.Path.String() + ": " + msg
+}
+
+// Unwrap returns the underlying error so errors.Is works with the sentinel
+// errors.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// notTableError returns a *PathError for a non-table node found at path.
+func notTableError(path Path, n *Node) *PathError {
+	return &PathError{Path: path.clone(), Err: ErrNotTable, Expected: NodeTypeTable, Actual: n.GetType()}
+}
+
+// WithPath returns err with keys prepended to its path. An error that isn't
+// a *PathError is wrapped in one. UnmarshalLua and MarshalLua methods can use
+// it to say where in their value an error occurred; decoding and encoding
+// then prepend the path to the value itself.
+func WithPath(err error, keys ...*Node) error {
+	if err == nil {
+		return nil
+	}
+	if pathErr, ok := err.(*PathError); ok {
+		e := *pathErr
+		e.Path = append(Path(keys).clone(), pathErr.Path...)
+		return &e
+	}
+	return &PathError{Path: Path(keys).clone(), Err: err}
+}
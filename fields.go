@@ -0,0 +1,68 @@
+package wowlua
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A structField describes how a struct field maps to a table key.
+type structField struct {
+	name      string // Table key
+	index     []int  // For reflect.Value.FieldByIndex
+	omitEmpty bool
+}
+
+var structFieldCache sync.Map // reflect.Type to []structField
+
+// structFields returns the fields of a struct type that map to table keys.
+// The key is the field name unless a `lua:"key"` tag gives another; a tag
+// of "-" skips the field. Fields of embedded structs without a tag are
+// treated as fields of the outer struct, which takes precedence.
+func structFields(t reflect.Type) []structField {
+	if f, ok := structFieldCache.Load(t); ok {
+		return f.([]structField)
+	}
+	fields := appendStructFields(nil, t, nil, make(map[string]bool))
+	f, _ := structFieldCache.LoadOrStore(t, fields)
+	return f.([]structField)
+}
+
+func appendStructFields(fields []structField, t reflect.Type, index []int, seen map[string]bool) []structField {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("lua")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && !hasTag && sf.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, sf)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // Unexported
+		}
+		f := structField{name: sf.Name, index: append(append([]int(nil), index...), i)}
+		if hasTag {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				f.name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					f.omitEmpty = true
+				}
+			}
+		}
+		if seen[f.name] {
+			continue
+		}
+		seen[f.name] = true
+		fields = append(fields, f)
+	}
+	for _, sf := range embedded {
+		fields = appendStructFields(fields, sf.Type, append(append([]int(nil), index...), sf.Index...), seen)
+	}
+	return fields
+}
@@ -0,0 +1,391 @@
+package wowlua
+
+import (
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+)
+
+// ImmutableTable is a persistent table: it never changes after creation.
+// With and Without return new tables that share all unchanged structure with
+// the original, including nested tables, so keeping many versions of a large
+// tree costs little more than the differences between them.
+//
+// Keys and values are Values. Nested tables are *ImmutableTable; a *Table
+// given to With is frozen first. ImmutableTables appear only inside other
+// ImmutableTables; use Thaw to get a mutable *Table back. NodeOf thaws an
+// ImmutableTable, so nodes only ever hold a *Table.
+//
+// ImmutableTables are safe for concurrent use.
+type ImmutableTable struct {
+	root    *hamtNode
+	size    int
+	nextSeq uint64
+}
+
+// Entries are stored in a hash array mapped trie. Each level uses five bits
+// of the key's hash to pick a slot. Slots hold either a sub-trie or the
+// entries whose hashes share the path to that slot.
+type hamtNode struct {
+	bitmap uint32
+	slots  []hamtSlot
+}
+
+type hamtSlot struct {
+	sub     *hamtNode
+	entries []immutableEntry
+}
+
+type immutableEntry struct {
+	hash uint64
+	key  tableKey
+	k    Value
+	v    Value
+	seq  uint64 // Insertion order, kept when a key's value is replaced
+}
+
+const hamtBits = 5
+
+var emptyHamt = &hamtNode{}
+
+// NewImmutableTable returns an empty ImmutableTable.
+func NewImmutableTable() *ImmutableTable {
+	return &ImmutableTable{root: emptyHamt}
+}
+
+// Freeze returns an immutable copy of the table. Nested tables are frozen
+// recursively.
+func Freeze(t *Table) *ImmutableTable {
+	it := NewImmutableTable()
+	for _, e := range t.entries {
+		it = it.With(e.key.Value(), e.value.Value())
+	}
+	return it
+}
+
+// Type returns NodeTypeTable
+func (*ImmutableTable) Type() NodeType { return NodeTypeTable }
+
+func (*ImmutableTable) isValue() {}
+
+// Len returns the number of entries in the table.
+func (t *ImmutableTable) Len() int {
+	return t.size
+}
+
+// Get returns the value for the key, or nil if there isn't one.
+func (t *ImmutableTable) Get(k Value) Value {
+	key := immutableKey(k)
+	h := hashKey(key)
+	n := t.root
+	for shift := uint(0); ; shift += hamtBits {
+		bit := uint32(1) << ((h >> shift) & 31)
+		if n.bitmap&bit == 0 {
+			return nil
+		}
+		slot := n.slots[bits.OnesCount32(n.bitmap&(bit-1))]
+		if slot.sub == nil {
+			for _, e := range slot.entries {
+				if e.key == key {
+					return e.v
+				}
+			}
+			return nil
+		}
+		n = slot.sub
+	}
+}
+
+// Has returns whether the table has an entry for the key.
+func (t *ImmutableTable) Has(k Value) bool {
+	return t.Get(k) != nil
+}
+
+// GetTable returns the nested table for the key, or nil if the value is
+// missing or isn't a table.
+func (t *ImmutableTable) GetTable(k Value) *ImmutableTable {
+	sub, _ := t.Get(k).(*ImmutableTable)
+	return sub
+}
+
+// With returns a table with the key set to the value. As with Table.Set,
+// setting a key to Nil removes it.
+func (t *ImmutableTable) With(k, v Value) *ImmutableTable {
+	switch tv := v.(type) {
+	case nil, Nil:
+		return t.Without(k)
+	case *Table:
+		v = Freeze(tv)
+	}
+	if kt, ok := k.(*Table); ok {
+		k = Freeze(kt)
+	}
+	key := immutableKey(k)
+	e := immutableEntry{hash: hashKey(key), key: key, k: k, v: v, seq: t.nextSeq}
+	root, added := t.root.with(0, e)
+	nt := &ImmutableTable{root: root, size: t.size, nextSeq: t.nextSeq}
+	if added {
+		nt.size++
+		nt.nextSeq++
+	}
+	return nt
+}
+
+// Without returns a table without an entry for the key. If there is no such
+// entry t itself is returned.
+func (t *ImmutableTable) Without(k Value) *ImmutableTable {
+	key := immutableKey(k)
+	root, removed := t.root.without(0, hashKey(key), key)
+	if !removed {
+		return t
+	}
+	return &ImmutableTable{root: root, size: t.size - 1, nextSeq: t.nextSeq}
+}
+
+// Keys returns the table's keys in the order they were first added.
+func (t *ImmutableTable) Keys() []Value {
+	entries := t.sortedEntries()
+	keys := make([]Value, len(entries))
+	for i, e := range entries {
+		keys[i] = e.k
+	}
+	return keys
+}
+
+// Thaw returns a mutable copy of the table, with nested tables thawed
+// recursively. Entries are in the order they were first added.
+func (t *ImmutableTable) Thaw() *Table {
+	m := NewTable()
+	for _, e := range t.sortedEntries() {
+		m.Set(NodeOf(thawValue(e.k)), NodeOf(thawValue(e.v)))
+	}
+	return m
+}
+
+// Update returns an immutable copy of m that shares every nested table that
+// is unchanged from t. If m is entirely unchanged, t itself is returned. This
+// is the way to snapshot successive versions of a table that is parsed or
+// edited as a mutable *Table.
+func (t *ImmutableTable) Update(m *Table) *ImmutableTable {
+	nt := NewImmutableTable()
+	changed := t.Len() != m.Len()
+	for _, e := range m.entries {
+		k := e.key.Value()
+		prev := t.Get(k)
+		v := e.value.Value()
+		switch mv := v.(type) {
+		case *Table:
+			if prevT, ok := prev.(*ImmutableTable); ok {
+				v = prevT.Update(mv)
+			}
+		default:
+			if _, isTable := prev.(*ImmutableTable); prev != nil && !isTable && NodeOf(prev).Equals(e.value) {
+				v = prev
+			}
+		}
+		if v != prev {
+			changed = true
+		}
+		nt = nt.With(k, v)
+	}
+	if !changed {
+		return t
+	}
+	return nt
+}
+
+// Equals returns whether the tables have the same keys and equal values.
+func (t *ImmutableTable) Equals(o *ImmutableTable) bool {
+	if t == o {
+		return true
+	}
+	if t.size != o.size {
+		return false
+	}
+	equal := true
+	t.root.each(func(e immutableEntry) bool {
+		equal = valuesEqual(e.v, o.Get(e.k))
+		return equal
+	})
+	return equal
+}
+
+func (t *ImmutableTable) sortedEntries() []immutableEntry {
+	entries := make([]immutableEntry, 0, t.size)
+	t.root.each(func(e immutableEntry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	return entries
+}
+
+// immutableKey returns the tableKey for k. Tables used as keys compare by
+// identity, so an *ImmutableTable key is used as it is rather than thawed
+// as NodeOf would.
+func immutableKey(k Value) tableKey {
+	if it, ok := k.(*ImmutableTable); ok {
+		return tableKey{NodeTypeTable, it}
+	}
+	return keyOf(NodeOf(k))
+}
+
+func thawValue(v Value) Value {
+	if it, ok := v.(*ImmutableTable); ok {
+		return it.Thaw()
+	}
+	return v
+}
+
+func valuesEqual(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	at, aOk := a.(*ImmutableTable)
+	bt, bOk := b.(*ImmutableTable)
+	if aOk || bOk {
+		return aOk && bOk && at.Equals(bt)
+	}
+	return NodeOf(a).Equals(NodeOf(b))
+}
+
+// with returns a copy of the node with the entry added or replaced and
+// whether it was added.
+func (n *hamtNode) with(shift uint, e immutableEntry) (*hamtNode, bool) {
+	bit := uint32(1) << ((e.hash >> shift) & 31)
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	if n.bitmap&bit == 0 {
+		nn := &hamtNode{bitmap: n.bitmap | bit, slots: make([]hamtSlot, len(n.slots)+1)}
+		copy(nn.slots, n.slots[:pos])
+		nn.slots[pos] = hamtSlot{entries: []immutableEntry{e}}
+		copy(nn.slots[pos+1:], n.slots[pos:])
+		return nn, true
+	}
+
+	slot := n.slots[pos]
+	added := false
+	switch {
+	case slot.sub != nil:
+		slot.sub, added = slot.sub.with(shift+hamtBits, e)
+	case slot.entries[0].hash == e.hash:
+		entries := make([]immutableEntry, 0, len(slot.entries)+1)
+		added = true
+		for _, old := range slot.entries {
+			if old.key == e.key {
+				e.seq = old.seq
+				added = false
+				continue
+			}
+			entries = append(entries, old)
+		}
+		slot.entries = append(entries, e)
+	default:
+		sub := emptyHamt
+		for _, old := range slot.entries {
+			sub, _ = sub.with(shift+hamtBits, old)
+		}
+		slot.sub, _ = sub.with(shift+hamtBits, e)
+		slot.entries = nil
+		added = true
+	}
+	nn := &hamtNode{bitmap: n.bitmap, slots: make([]hamtSlot, len(n.slots))}
+	copy(nn.slots, n.slots)
+	nn.slots[pos] = slot
+	return nn, added
+}
+
+// without returns a copy of the node with the key removed and whether it was
+// there to remove.
+func (n *hamtNode) without(shift uint, hash uint64, key tableKey) (*hamtNode, bool) {
+	bit := uint32(1) << ((hash >> shift) & 31)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	slot := n.slots[pos]
+	if slot.sub != nil {
+		sub, removed := slot.sub.without(shift+hamtBits, hash, key)
+		if !removed {
+			return n, false
+		}
+		slot.sub = sub
+		if len(sub.slots) == 0 {
+			slot.sub = nil
+		}
+	} else {
+		entries := make([]immutableEntry, 0, len(slot.entries))
+		for _, e := range slot.entries {
+			if e.key != key {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == len(slot.entries) {
+			return n, false
+		}
+		slot.entries = entries
+	}
+
+	if slot.sub == nil && len(slot.entries) == 0 {
+		nn := &hamtNode{bitmap: n.bitmap &^ bit, slots: make([]hamtSlot, 0, len(n.slots)-1)}
+		nn.slots = append(nn.slots, n.slots[:pos]...)
+		nn.slots = append(nn.slots, n.slots[pos+1:]...)
+		return nn, true
+	}
+	nn := &hamtNode{bitmap: n.bitmap, slots: make([]hamtSlot, len(n.slots))}
+	copy(nn.slots, n.slots)
+	nn.slots[pos] = slot
+	return nn, true
+}
+
+// each calls fn for every entry under the node until fn returns false.
+func (n *hamtNode) each(fn func(immutableEntry) bool) bool {
+	for _, slot := range n.slots {
+		if slot.sub != nil {
+			if !slot.sub.each(fn) {
+				return false
+			}
+			continue
+		}
+		for _, e := range slot.entries {
+			if !fn(e) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hashKey hashes a table key with FNV-1a.
+func hashKey(k tableKey) uint64 {
+	const prime = 1099511628211
+	h := uint64(14695981039346656037)
+	h = (h ^ uint64(k.nType)) * prime
+	var u uint64
+	switch v := k.value.(type) {
+	case string:
+		for i := 0; i < len(v); i++ {
+			h = (h ^ uint64(v[i])) * prime
+		}
+		return h
+	case int64:
+		u = uint64(v)
+	case float64:
+		u = math.Float64bits(v)
+	case bool:
+		if v {
+			u = 1
+		}
+	default:
+		// Tables hash by identity. Other values, such as Nil, can't be keys
+		// and share a fixed hash.
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+			u = uint64(rv.Pointer())
+		}
+	}
+	for i := 0; i < 8; i++ {
+		h = (h ^ (u & 0xff)) * prime
+		u >>= 8
+	}
+	return h
+}
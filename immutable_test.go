@@ -0,0 +1,85 @@
+package wowlua
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestImmutableTable(t *testing.T) {
+	empty := NewImmutableTable()
+	it := empty
+	for i := 0; i < 1000; i++ {
+		it = it.With(Str(fmt.Sprint("key", i)), Int(int64(i)))
+	}
+	if it.Len() != 1000 || empty.Len() != 0 {
+		t.Fatalf("Expected 1000 and 0 entries, got %v and %v", it.Len(), empty.Len())
+	}
+	changed := it.With(Str("key5"), Str("five")).Without(Str("key6"))
+	if it.Get(Str("key5")) != Int(5) || !it.Has(Str("key6")) {
+		t.Errorf("Expected the original to be unchanged")
+	}
+	if changed.Get(Str("key5")) != Str("five") || changed.Has(Str("key6")) || changed.Len() != 999 {
+		t.Errorf("Expected key5 replaced and key6 removed")
+	}
+	if keys := changed.Keys(); keys[5] != Str("key5") || keys[6] != Str("key7") {
+		t.Errorf("Expected insertion order to be kept, got %v %v", keys[5], keys[6])
+	}
+	for i := 0; i < 1000; i++ {
+		it = it.Without(Str(fmt.Sprint("key", i)))
+	}
+	if it.Len() != 0 || !it.Equals(empty) {
+		t.Errorf("Expected removing every key to leave an empty table")
+	}
+}
+
+func TestImmutableTableSharing(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	first := Freeze(tab)
+	if !first.Thaw().Equals(tab) {
+		t.Fatalf("Expected a frozen and thawed table to equal the original")
+	}
+	if first.Update(tab) != first {
+		t.Errorf("Expected updating with an unchanged table to return the same snapshot")
+	}
+
+	tab.SetPath(NewNode(NodeTypeString, "demote"), NewPath("HarbingerTools_GuildLog", "Moon Guard", "Harbingers of Discord", 1, "type")...)
+	second := first.Update(tab)
+	if second == first {
+		t.Fatalf("Expected a new snapshot after a change")
+	}
+	if first.GetTable(Str("HarbingerTools_Events")) != second.GetTable(Str("HarbingerTools_Events")) {
+		t.Errorf("Expected the unchanged subtree to be shared")
+	}
+	log := func(it *ImmutableTable) *ImmutableTable {
+		return it.GetTable(Str("HarbingerTools_GuildLog")).GetTable(Str("Moon Guard")).GetTable(Str("Harbingers of Discord"))
+	}
+	if log(first).GetTable(Int(2)) != log(second).GetTable(Int(2)) {
+		t.Errorf("Expected unchanged siblings of the change to be shared")
+	}
+	if log(first).GetTable(Int(1)).Get(Str("type")) != Str("promote") {
+		t.Errorf("Expected the first snapshot to be unchanged")
+	}
+	if !second.Thaw().Equals(tab) {
+		t.Errorf("Expected the second snapshot to match the modified table")
+	}
+}
+
+func TestImmutableTableValues(t *testing.T) {
+	it := NewImmutableTable().With(Str("name"), Str("x"))
+	if it.Get(Nil{}) != nil || it.Has(nil) {
+		t.Errorf("Expected no entry for a nil key")
+	}
+	n := NodeOf(it)
+	if n.GetType() != NodeTypeTable || n.GetTable() == nil || n.GetTable().GetByString("name").GetString() != "x" {
+		t.Errorf("Expected NodeOf to thaw the table, got %v", n)
+	}
+
+	key := NewImmutableTable()
+	keyed := NewImmutableTable().With(key, Str("table key"))
+	if keyed.Get(key) != Str("table key") || keyed.Has(NewImmutableTable()) {
+		t.Errorf("Expected table keys to compare by identity")
+	}
+}
@@ -0,0 +1,48 @@
+package wowlua
+
+import (
+	"sync"
+)
+
+// An Interner deduplicates table keys while parsing. SavedVariables files
+// repeat the same few keys for every record, so sharing one copy of each key
+// greatly reduces the memory used by a parsed tree. An Interner may be shared
+// by parsers running at once, which also shares keys between the trees they
+// produce.
+type Interner struct {
+	mu   sync.Mutex
+	keys map[string]*Node
+}
+
+// NewInterner creates an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{keys: make(map[string]*Node)}
+}
+
+// Intern returns the interned copy of s, adding s if it's new.
+func (in *Interner) Intern(s string) string {
+	return in.key(s).GetString()
+}
+
+// Len returns the number of distinct strings interned.
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.keys)
+}
+
+// key returns the shared string key node for s. Nodes are never modified so
+// a key node may appear in any number of tables.
+func (in *Interner) key(s string) *Node {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	n, ok := in.keys[s]
+	if !ok {
+		// Copy s so the key doesn't keep the whole input it was sliced from
+		// in memory.
+		s = string([]byte(s))
+		n = NewNode(NodeTypeString, s)
+		in.keys[s] = n
+	}
+	return n
+}
@@ -0,0 +1,48 @@
+// Package gentest holds types with luacodec-generated methods, for testing
+// them against the reflective decoder and encoder.
+package gentest
+
+import "time"
+
+//go:generate go run ../../cmd/luacodec
+
+// Calendar is shaped like the HarbingerTools_Events saved variable.
+//
+//luacodec:generate
+type Calendar struct {
+	Guilds     map[string][]*Event `lua:"Guilds"`
+	Characters map[string]Character
+	Version    int `lua:"version,omitempty"`
+}
+
+//luacodec:generate
+type Character struct {
+	Level  uint8             `lua:"level"`
+	Gold   float64           `lua:"gold,omitempty"`
+	Counts map[int]int       `lua:"counts,omitempty"`
+	Flags  []bool            `lua:"flags,omitempty"`
+	Bank   Copper            `lua:"bank,omitempty"`
+	Played time.Duration     `lua:"played,omitempty"`
+	Extra  map[string]string `lua:"-"`
+}
+
+// Copper is an amount of money in copper pieces.
+type Copper int64
+
+//luacodec:generate
+type Event struct {
+	Title        string      `lua:"title"`
+	Year         int         `lua:"nowYear"`
+	Month        int8        `lua:"month"`
+	Day          int         `lua:"day"`
+	Hour         int         `lua:"hour"`
+	Minute       int         `lua:"minute"`
+	EventType    int         `lua:"eventType"`
+	InviteStatus int         `lua:"inviteStatus"`
+	InviteType   int         `lua:"inviteType"`
+	CalendarType string      `lua:"calendarType"`
+	InvitedBy    string      `lua:"invitedBy"`
+	ModStatus    string      `lua:"modStatus,omitempty"`
+	Note         *string     `lua:"note,omitempty"`
+	Raw          interface{} `lua:"raw,omitempty"`
+}
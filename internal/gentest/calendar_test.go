@@ -0,0 +1,179 @@
+package gentest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasonmf/wowlua"
+)
+
+// The plain types mirror the generated ones without their methods, so
+// Decode and NewTableFrom handle them by reflection.
+type plainCalendar struct {
+	Guilds     map[string][]*plainEvent `lua:"Guilds"`
+	Characters map[string]plainCharacter
+	Version    int `lua:"version,omitempty"`
+}
+
+type plainCharacter struct {
+	Level  uint8             `lua:"level"`
+	Gold   float64           `lua:"gold,omitempty"`
+	Counts map[int]int       `lua:"counts,omitempty"`
+	Flags  []bool            `lua:"flags,omitempty"`
+	Bank   Copper            `lua:"bank,omitempty"`
+	Played time.Duration     `lua:"played,omitempty"`
+	Extra  map[string]string `lua:"-"`
+}
+
+type plainEvent struct {
+	Title        string      `lua:"title"`
+	Year         int         `lua:"nowYear"`
+	Month        int8        `lua:"month"`
+	Day          int         `lua:"day"`
+	Hour         int         `lua:"hour"`
+	Minute       int         `lua:"minute"`
+	EventType    int         `lua:"eventType"`
+	InviteStatus int         `lua:"inviteStatus"`
+	InviteType   int         `lua:"inviteType"`
+	CalendarType string      `lua:"calendarType"`
+	InvitedBy    string      `lua:"invitedBy"`
+	ModStatus    string      `lua:"modStatus,omitempty"`
+	Note         *string     `lua:"note,omitempty"`
+	Raw          interface{} `lua:"raw,omitempty"`
+}
+
+// calendar returns a table shaped like the HarbingerTools_Events saved
+// variable with the given number of events.
+func calendar(events int) *wowlua.Table {
+	var b strings.Builder
+	b.WriteString("HarbingerTools_Events = {\n\t[\"Guilds\"] = {\n\t\t[\"Moon Guard\"] = {\n")
+	for i := 0; i < events; i++ {
+		fmt.Fprintf(&b, `			{
+				["nowYear"] = 2014,
+				["title"] = "Event %d",
+				["day"] = %d,
+				["inviteStatus"] = 8,
+				["modStatus"] = "",
+				["eventType"] = %d,
+				["month"] = -1,
+				["hour"] = %d,
+				["minute"] = %d,
+				["calendarType"] = "GUILD_EVENT",
+				["inviteType"] = 2,
+				["invitedBy"] = "Player%d",
+				["note"] = "bring flasks",
+				["raw"] = { 1, 2, ["x"] = true },
+				["unknown"] = 1,
+			}, -- [%d]
+`, i, i%28+1, i%6, i%24, i%60, i%100, i+1)
+	}
+	b.WriteString("\t\t},\n\t},\n\t[\"Characters\"] = {\n")
+	b.WriteString("\t\t[\"Harbinger\"] = { [\"level\"] = 90, [\"gold\"] = 1234.5, [\"counts\"] = { [3] = 4, [1] = 2 }, [\"flags\"] = { true, false }, [\"bank\"] = 250000, [\"played\"] = 3600000000000 },\n")
+	b.WriteString("\t\t[\"Alt\"] = { [\"level\"] = 12 },\n")
+	b.WriteString("\t},\n}\n")
+	t, err := wowlua.ParseLua(b.String())
+	if err != nil {
+		panic(err)
+	}
+	return t.GetByString("HarbingerTools_Events").GetTable()
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	table := calendar(3)
+	var generated Calendar
+	if err := table.Decode(&generated); err != nil {
+		t.Fatalf("Decode with generated methods: %v", err)
+	}
+	var plain plainCalendar
+	if err := table.Decode(&plain); err != nil {
+		t.Fatalf("Decode by reflection: %v", err)
+	}
+	if len(generated.Guilds["Moon Guard"]) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(generated.Guilds["Moon Guard"]))
+	}
+	if note := generated.Guilds["Moon Guard"][1].Note; note == nil || *note != "bring flasks" {
+		t.Errorf("Expected note \"bring flasks\", got %v", note)
+	}
+
+	generated_table, err := wowlua.NewTableFrom(generated)
+	if err != nil {
+		t.Fatalf("Marshal with generated methods: %v", err)
+	}
+	plain_table, err := wowlua.NewTableFrom(plain)
+	if err != nil {
+		t.Fatalf("Marshal by reflection: %v", err)
+	}
+	generated_text, _ := generated_table.EncodeCanonicalLua()
+	plain_text, _ := plain_table.EncodeCanonicalLua()
+	if string(generated_text) != string(plain_text) {
+		t.Errorf("Expected generated output\n%s\nto match reflective output\n%s", generated_text, plain_text)
+	}
+}
+
+func TestGeneratedErrors(t *testing.T) {
+	table := calendar(2)
+	table.GetByString("Guilds").GetTable().GetByString("Moon Guard").GetTable().GetIndexed(2).GetTable().Set(
+		wowlua.NewNode(wowlua.NodeTypeString, "month"), wowlua.NodeOf(wowlua.IntNumber(300)))
+
+	var generated Calendar
+	generated_err := table.Decode(&generated)
+	var plain plainCalendar
+	plain_err := table.Decode(&plain)
+	if !errors.Is(generated_err, wowlua.ErrOverflow) {
+		t.Fatalf("Expected ErrOverflow, got %v", generated_err)
+	}
+	if plain_err == nil || generated_err.Error() != plain_err.Error() {
+		t.Errorf("Expected error %q, got %q", plain_err, generated_err)
+	}
+}
+
+func BenchmarkDecodeGenerated(b *testing.B) {
+	table := calendar(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var c Calendar
+		if err := c.UnmarshalLua(wowlua.NodeOf(table)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeReflection(b *testing.B) {
+	table := calendar(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var c plainCalendar
+		if err := table.Decode(&c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeGenerated(b *testing.B) {
+	var c Calendar
+	if err := calendar(1000).Decode(&c); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.MarshalLua(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeReflection(b *testing.B) {
+	var c plainCalendar
+	if err := calendar(1000).Decode(&c); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := wowlua.NewTableFrom(c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,717 @@
+// Code generated by luacodec; DO NOT EDIT.
+
+package gentest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jasonmf/wowlua"
+)
+
+var (
+	luaKeyCalendarGuilds     = wowlua.NewNode(wowlua.NodeTypeString, "Guilds")
+	luaKeyCalendarCharacters = wowlua.NewNode(wowlua.NodeTypeString, "Characters")
+	luaKeyCalendarVersion    = wowlua.NewNode(wowlua.NodeTypeString, "version")
+)
+
+// UnmarshalLua implements wowlua.LuaUnmarshaler.
+func (v *Calendar) UnmarshalLua(n *wowlua.Node) error {
+	if n.IsNil() {
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	t.Range(func(k, val *wowlua.Node) bool {
+		if k.GetType() != wowlua.NodeTypeString {
+			return true
+		}
+		switch k.GetString() {
+		case "Guilds":
+			err = luaDecodeMapStringSlicePtrEvent(val, &v.Guilds)
+		case "Characters":
+			err = luaDecodeMapStringCharacter(val, &v.Characters)
+		case "version":
+			err = luaDecodeInt(val, &v.Version)
+		}
+		if err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// MarshalLua implements wowlua.LuaMarshaler.
+func (v Calendar) MarshalLua() (*wowlua.Node, error) {
+	t := wowlua.NewTable()
+	var (
+		n   *wowlua.Node
+		err error
+	)
+	n, err = luaEncodeMapStringSlicePtrEvent(v.Guilds)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyCalendarGuilds)
+	}
+	t.Set(luaKeyCalendarGuilds, n)
+	n, err = luaEncodeMapStringCharacter(v.Characters)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyCalendarCharacters)
+	}
+	t.Set(luaKeyCalendarCharacters, n)
+	if v.Version != 0 {
+		n, err = luaEncodeInt(v.Version)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyCalendarVersion)
+		}
+		t.Set(luaKeyCalendarVersion, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+var (
+	luaKeyCharacterLevel  = wowlua.NewNode(wowlua.NodeTypeString, "level")
+	luaKeyCharacterGold   = wowlua.NewNode(wowlua.NodeTypeString, "gold")
+	luaKeyCharacterCounts = wowlua.NewNode(wowlua.NodeTypeString, "counts")
+	luaKeyCharacterFlags  = wowlua.NewNode(wowlua.NodeTypeString, "flags")
+	luaKeyCharacterBank   = wowlua.NewNode(wowlua.NodeTypeString, "bank")
+	luaKeyCharacterPlayed = wowlua.NewNode(wowlua.NodeTypeString, "played")
+)
+
+// UnmarshalLua implements wowlua.LuaUnmarshaler.
+func (v *Character) UnmarshalLua(n *wowlua.Node) error {
+	if n.IsNil() {
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	t.Range(func(k, val *wowlua.Node) bool {
+		if k.GetType() != wowlua.NodeTypeString {
+			return true
+		}
+		switch k.GetString() {
+		case "level":
+			err = luaDecodeUint8(val, &v.Level)
+		case "gold":
+			err = luaDecodeFloat64(val, &v.Gold)
+		case "counts":
+			err = luaDecodeMapIntInt(val, &v.Counts)
+		case "flags":
+			err = luaDecodeSliceBool(val, &v.Flags)
+		case "bank":
+			err = luaDecodeCopper(val, &v.Bank)
+		case "played":
+			err = luaDecodeTimeDuration(val, &v.Played)
+		}
+		if err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// MarshalLua implements wowlua.LuaMarshaler.
+func (v Character) MarshalLua() (*wowlua.Node, error) {
+	t := wowlua.NewTable()
+	var (
+		n   *wowlua.Node
+		err error
+	)
+	n, err = luaEncodeUint8(v.Level)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyCharacterLevel)
+	}
+	t.Set(luaKeyCharacterLevel, n)
+	if v.Gold != 0 {
+		n, err = luaEncodeFloat64(v.Gold)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyCharacterGold)
+		}
+		t.Set(luaKeyCharacterGold, n)
+	}
+	if len(v.Counts) != 0 {
+		n, err = luaEncodeMapIntInt(v.Counts)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyCharacterCounts)
+		}
+		t.Set(luaKeyCharacterCounts, n)
+	}
+	if len(v.Flags) != 0 {
+		n, err = luaEncodeSliceBool(v.Flags)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyCharacterFlags)
+		}
+		t.Set(luaKeyCharacterFlags, n)
+	}
+	if v.Bank != 0 {
+		n, err = luaEncodeCopper(v.Bank)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyCharacterBank)
+		}
+		t.Set(luaKeyCharacterBank, n)
+	}
+	if v.Played != 0 {
+		n, err = luaEncodeTimeDuration(v.Played)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyCharacterPlayed)
+		}
+		t.Set(luaKeyCharacterPlayed, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+var (
+	luaKeyEventTitle        = wowlua.NewNode(wowlua.NodeTypeString, "title")
+	luaKeyEventYear         = wowlua.NewNode(wowlua.NodeTypeString, "nowYear")
+	luaKeyEventMonth        = wowlua.NewNode(wowlua.NodeTypeString, "month")
+	luaKeyEventDay          = wowlua.NewNode(wowlua.NodeTypeString, "day")
+	luaKeyEventHour         = wowlua.NewNode(wowlua.NodeTypeString, "hour")
+	luaKeyEventMinute       = wowlua.NewNode(wowlua.NodeTypeString, "minute")
+	luaKeyEventEventType    = wowlua.NewNode(wowlua.NodeTypeString, "eventType")
+	luaKeyEventInviteStatus = wowlua.NewNode(wowlua.NodeTypeString, "inviteStatus")
+	luaKeyEventInviteType   = wowlua.NewNode(wowlua.NodeTypeString, "inviteType")
+	luaKeyEventCalendarType = wowlua.NewNode(wowlua.NodeTypeString, "calendarType")
+	luaKeyEventInvitedBy    = wowlua.NewNode(wowlua.NodeTypeString, "invitedBy")
+	luaKeyEventModStatus    = wowlua.NewNode(wowlua.NodeTypeString, "modStatus")
+	luaKeyEventNote         = wowlua.NewNode(wowlua.NodeTypeString, "note")
+	luaKeyEventRaw          = wowlua.NewNode(wowlua.NodeTypeString, "raw")
+)
+
+// UnmarshalLua implements wowlua.LuaUnmarshaler.
+func (v *Event) UnmarshalLua(n *wowlua.Node) error {
+	if n.IsNil() {
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	t.Range(func(k, val *wowlua.Node) bool {
+		if k.GetType() != wowlua.NodeTypeString {
+			return true
+		}
+		switch k.GetString() {
+		case "title":
+			err = luaDecodeString(val, &v.Title)
+		case "nowYear":
+			err = luaDecodeInt(val, &v.Year)
+		case "month":
+			err = luaDecodeInt8(val, &v.Month)
+		case "day":
+			err = luaDecodeInt(val, &v.Day)
+		case "hour":
+			err = luaDecodeInt(val, &v.Hour)
+		case "minute":
+			err = luaDecodeInt(val, &v.Minute)
+		case "eventType":
+			err = luaDecodeInt(val, &v.EventType)
+		case "inviteStatus":
+			err = luaDecodeInt(val, &v.InviteStatus)
+		case "inviteType":
+			err = luaDecodeInt(val, &v.InviteType)
+		case "calendarType":
+			err = luaDecodeString(val, &v.CalendarType)
+		case "invitedBy":
+			err = luaDecodeString(val, &v.InvitedBy)
+		case "modStatus":
+			err = luaDecodeString(val, &v.ModStatus)
+		case "note":
+			err = luaDecodePtrString(val, &v.Note)
+		case "raw":
+			err = luaDecodeInterface(val, &v.Raw)
+		}
+		if err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// MarshalLua implements wowlua.LuaMarshaler.
+func (v Event) MarshalLua() (*wowlua.Node, error) {
+	t := wowlua.NewTable()
+	var (
+		n   *wowlua.Node
+		err error
+	)
+	n, err = luaEncodeString(v.Title)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventTitle)
+	}
+	t.Set(luaKeyEventTitle, n)
+	n, err = luaEncodeInt(v.Year)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventYear)
+	}
+	t.Set(luaKeyEventYear, n)
+	n, err = luaEncodeInt8(v.Month)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventMonth)
+	}
+	t.Set(luaKeyEventMonth, n)
+	n, err = luaEncodeInt(v.Day)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventDay)
+	}
+	t.Set(luaKeyEventDay, n)
+	n, err = luaEncodeInt(v.Hour)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventHour)
+	}
+	t.Set(luaKeyEventHour, n)
+	n, err = luaEncodeInt(v.Minute)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventMinute)
+	}
+	t.Set(luaKeyEventMinute, n)
+	n, err = luaEncodeInt(v.EventType)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventEventType)
+	}
+	t.Set(luaKeyEventEventType, n)
+	n, err = luaEncodeInt(v.InviteStatus)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventInviteStatus)
+	}
+	t.Set(luaKeyEventInviteStatus, n)
+	n, err = luaEncodeInt(v.InviteType)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventInviteType)
+	}
+	t.Set(luaKeyEventInviteType, n)
+	n, err = luaEncodeString(v.CalendarType)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventCalendarType)
+	}
+	t.Set(luaKeyEventCalendarType, n)
+	n, err = luaEncodeString(v.InvitedBy)
+	if err != nil {
+		return nil, wowlua.WithPath(err, luaKeyEventInvitedBy)
+	}
+	t.Set(luaKeyEventInvitedBy, n)
+	if v.ModStatus != "" {
+		n, err = luaEncodeString(v.ModStatus)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyEventModStatus)
+		}
+		t.Set(luaKeyEventModStatus, n)
+	}
+	if v.Note != nil {
+		n, err = luaEncodePtrString(v.Note)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyEventNote)
+		}
+		t.Set(luaKeyEventNote, n)
+	}
+	if v.Raw != nil {
+		n, err = luaEncodeInterface(v.Raw)
+		if err != nil {
+			return nil, wowlua.WithPath(err, luaKeyEventRaw)
+		}
+		t.Set(luaKeyEventRaw, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+func luaDecodeMapStringSlicePtrEvent(n *wowlua.Node, x *map[string][]*Event) error {
+	if n.IsNil() {
+		*x = nil
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	if *x == nil {
+		*x = make(map[string][]*Event, t.Len())
+	}
+	t.Range(func(k, v *wowlua.Node) bool {
+		var key string
+		if err = luaDecodeString(k, &key); err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		var value []*Event
+		if err = luaDecodeSlicePtrEvent(v, &value); err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		(*x)[key] = value
+		return true
+	})
+	return err
+}
+
+func luaEncodeMapStringSlicePtrEvent(x map[string][]*Event) (*wowlua.Node, error) {
+	if x == nil {
+		return wowlua.NodeOf(nil), nil
+	}
+	keys := make([]string, 0, len(x))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	t := wowlua.NewTable()
+	for _, key := range keys {
+		k, err := luaEncodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		n, err := luaEncodeSlicePtrEvent(x[key])
+		if err != nil {
+			return nil, wowlua.WithPath(err, k)
+		}
+		t.Set(k, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+func luaDecodeMapStringCharacter(n *wowlua.Node, x *map[string]Character) error {
+	if n.IsNil() {
+		*x = nil
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	if *x == nil {
+		*x = make(map[string]Character, t.Len())
+	}
+	t.Range(func(k, v *wowlua.Node) bool {
+		var key string
+		if err = luaDecodeString(k, &key); err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		var value Character
+		if err = luaDecodeCharacter(v, &value); err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		(*x)[key] = value
+		return true
+	})
+	return err
+}
+
+func luaEncodeMapStringCharacter(x map[string]Character) (*wowlua.Node, error) {
+	if x == nil {
+		return wowlua.NodeOf(nil), nil
+	}
+	keys := make([]string, 0, len(x))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	t := wowlua.NewTable()
+	for _, key := range keys {
+		k, err := luaEncodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		n, err := luaEncodeCharacter(x[key])
+		if err != nil {
+			return nil, wowlua.WithPath(err, k)
+		}
+		t.Set(k, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+func luaDecodeInt(n *wowlua.Node, x *int) error {
+	i, err := n.AsInt()
+	if err != nil {
+		return err
+	}
+	if int64(int(i)) != i {
+		return &wowlua.PathError{Err: wowlua.ErrOverflow}
+	}
+	*x = int(i)
+	return nil
+}
+
+func luaEncodeInt(x int) (*wowlua.Node, error) {
+	return wowlua.NodeOf(wowlua.IntNumber(int64(x))), nil
+}
+
+func luaDecodeUint8(n *wowlua.Node, x *uint8) error {
+	i, err := n.AsInt()
+	if err != nil {
+		return err
+	}
+	if i < 0 || uint64(uint8(i)) != uint64(i) {
+		return &wowlua.PathError{Err: wowlua.ErrOverflow}
+	}
+	*x = uint8(i)
+	return nil
+}
+
+func luaEncodeUint8(x uint8) (*wowlua.Node, error) {
+	return wowlua.NodeOf(wowlua.IntNumber(int64(x))), nil
+}
+
+func luaDecodeFloat64(n *wowlua.Node, x *float64) error {
+	f, err := n.AsFloat()
+	if err != nil {
+		return err
+	}
+	*x = f
+	return nil
+}
+
+func luaEncodeFloat64(x float64) (*wowlua.Node, error) {
+	return wowlua.NodeOf(wowlua.FloatNumber(x)), nil
+}
+
+func luaDecodeMapIntInt(n *wowlua.Node, x *map[int]int) error {
+	if n.IsNil() {
+		*x = nil
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	if *x == nil {
+		*x = make(map[int]int, t.Len())
+	}
+	t.Range(func(k, v *wowlua.Node) bool {
+		var key int
+		if err = luaDecodeInt(k, &key); err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		var value int
+		if err = luaDecodeInt(v, &value); err != nil {
+			err = wowlua.WithPath(err, k)
+			return false
+		}
+		(*x)[key] = value
+		return true
+	})
+	return err
+}
+
+func luaEncodeMapIntInt(x map[int]int) (*wowlua.Node, error) {
+	if x == nil {
+		return wowlua.NodeOf(nil), nil
+	}
+	keys := make([]int, 0, len(x))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	t := wowlua.NewTable()
+	for _, key := range keys {
+		k, err := luaEncodeInt(key)
+		if err != nil {
+			return nil, err
+		}
+		n, err := luaEncodeInt(x[key])
+		if err != nil {
+			return nil, wowlua.WithPath(err, k)
+		}
+		t.Set(k, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+func luaDecodeSliceBool(n *wowlua.Node, x *[]bool) error {
+	if n.IsNil() {
+		*x = nil
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	s := make([]bool, t.SeqLen())
+	for i := range s {
+		v := t.GetIndexed(i + 1)
+		if err := luaDecodeBool(v, &s[i]); err != nil {
+			return wowlua.WithPath(err, wowlua.NodeOf(wowlua.IntNumber(int64(i+1))))
+		}
+	}
+	*x = s
+	return nil
+}
+
+func luaEncodeSliceBool(x []bool) (*wowlua.Node, error) {
+	if x == nil {
+		return wowlua.NodeOf(nil), nil
+	}
+	t := wowlua.NewTable()
+	for i, e := range x {
+		k := wowlua.NodeOf(wowlua.IntNumber(int64(i + 1)))
+		n, err := luaEncodeBool(e)
+		if err != nil {
+			return nil, wowlua.WithPath(err, k)
+		}
+		t.Set(k, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+func luaDecodeCopper(n *wowlua.Node, x *Copper) error {
+	return n.Decode(x)
+}
+
+func luaEncodeCopper(x Copper) (*wowlua.Node, error) {
+	return wowlua.FromGo(x)
+}
+
+func luaDecodeTimeDuration(n *wowlua.Node, x *time.Duration) error {
+	return n.Decode(x)
+}
+
+func luaEncodeTimeDuration(x time.Duration) (*wowlua.Node, error) {
+	return wowlua.FromGo(x)
+}
+
+func luaDecodeString(n *wowlua.Node, x *string) error {
+	s, err := n.AsString()
+	if err != nil {
+		return err
+	}
+	*x = s
+	return nil
+}
+
+func luaEncodeString(x string) (*wowlua.Node, error) {
+	return wowlua.NodeOf(wowlua.String(x)), nil
+}
+
+func luaDecodeInt8(n *wowlua.Node, x *int8) error {
+	i, err := n.AsInt()
+	if err != nil {
+		return err
+	}
+	if int64(int8(i)) != i {
+		return &wowlua.PathError{Err: wowlua.ErrOverflow}
+	}
+	*x = int8(i)
+	return nil
+}
+
+func luaEncodeInt8(x int8) (*wowlua.Node, error) {
+	return wowlua.NodeOf(wowlua.IntNumber(int64(x))), nil
+}
+
+func luaDecodePtrString(n *wowlua.Node, x **string) error {
+	if n.IsNil() {
+		*x = nil
+		return nil
+	}
+	if *x == nil {
+		*x = new(string)
+	}
+	return luaDecodeString(n, *x)
+}
+
+func luaEncodePtrString(x *string) (*wowlua.Node, error) {
+	if x == nil {
+		return wowlua.NodeOf(nil), nil
+	}
+	return luaEncodeString(*x)
+}
+
+func luaDecodeInterface(n *wowlua.Node, x *interface{}) error {
+	return n.Decode(x)
+}
+
+func luaEncodeInterface(x interface{}) (*wowlua.Node, error) {
+	return wowlua.FromGo(x)
+}
+
+func luaDecodeSlicePtrEvent(n *wowlua.Node, x *[]*Event) error {
+	if n.IsNil() {
+		*x = nil
+		return nil
+	}
+	t, err := n.AsTable()
+	if err != nil {
+		return err
+	}
+	s := make([]*Event, t.SeqLen())
+	for i := range s {
+		v := t.GetIndexed(i + 1)
+		if err := luaDecodePtrEvent(v, &s[i]); err != nil {
+			return wowlua.WithPath(err, wowlua.NodeOf(wowlua.IntNumber(int64(i+1))))
+		}
+	}
+	*x = s
+	return nil
+}
+
+func luaEncodeSlicePtrEvent(x []*Event) (*wowlua.Node, error) {
+	if x == nil {
+		return wowlua.NodeOf(nil), nil
+	}
+	t := wowlua.NewTable()
+	for i, e := range x {
+		k := wowlua.NodeOf(wowlua.IntNumber(int64(i + 1)))
+		n, err := luaEncodePtrEvent(e)
+		if err != nil {
+			return nil, wowlua.WithPath(err, k)
+		}
+		t.Set(k, n)
+	}
+	return wowlua.NodeOf(t), nil
+}
+
+func luaDecodeCharacter(n *wowlua.Node, x *Character) error {
+	return x.UnmarshalLua(n)
+}
+
+func luaEncodeCharacter(x Character) (*wowlua.Node, error) {
+	return x.MarshalLua()
+}
+
+func luaDecodeBool(n *wowlua.Node, x *bool) error {
+	v, err := n.AsBool()
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+func luaEncodeBool(x bool) (*wowlua.Node, error) {
+	return wowlua.NodeOf(wowlua.Bool(x)), nil
+}
+
+func luaDecodePtrEvent(n *wowlua.Node, x **Event) error {
+	if n.IsNil() {
+		*x = nil
+		return nil
+	}
+	if *x == nil {
+		*x = new(Event)
+	}
+	return luaDecodeEvent(n, *x)
+}
+
+func luaEncodePtrEvent(x *Event) (*wowlua.Node, error) {
+	if x == nil {
+		return wowlua.NodeOf(nil), nil
+	}
+	return luaEncodeEvent(*x)
+}
+
+func luaDecodeEvent(n *wowlua.Node, x *Event) error {
+	return x.UnmarshalLua(n)
+}
+
+func luaEncodeEvent(x Event) (*wowlua.Node, error) {
+	return x.MarshalLua()
+}
@@ -0,0 +1,459 @@
+package wowlua
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// JSONMode selects how ToJSON writes tables.
+type JSONMode int
+
+const (
+	// JSONArrays writes tables whose keys are exactly 1 to n as arrays and
+	// other tables, including empty ones, as objects. Keys are converted to
+	// strings the way Lua's tostring does, so 1 becomes "1" and the entries
+	// for 1 and "1" would collide; the later one wins.
+	JSONArrays JSONMode = iota
+	// JSONObjects writes every table as an object, with keys converted to
+	// strings as JSONArrays does.
+	JSONObjects
+	// JSONTagged writes a lossless encoding that FromJSONTagged converts
+	// back to the same tree. Strings, bools and integers are written as JSON
+	// values of the same type, except that a string that isn't valid UTF-8
+	// is written as {"bytes": b}, with b its bytes in standard base64, so
+	// data such as compressed strings survives. A float is written as
+	// {"float": f}, with f "inf",
+	// "-inf" or "nan" for those values, so it stays a float even after
+	// passing through a JSON library that doesn't distinguish 1.0 from 1. A
+	// table is written as {"table": [[key, value], ...]}, with keys and
+	// values in the same encoding and entries in the table's order.
+	JSONTagged
+)
+
+// JSONOptions controls how tables are written as JSON. The zero value is
+// what MarshalJSON uses.
+type JSONOptions struct {
+	// Mode selects how tables are written.
+	Mode JSONMode
+	// Indent, if not empty, writes each array element and object entry on
+	// its own line, indented with Indent once per nesting level.
+	Indent string
+}
+
+// MarshalJSON implements json.Marshaler, writing the table with the default
+// JSONOptions.
+func (t *Table) MarshalJSON() ([]byte, error) {
+	return t.ToJSON(JSONOptions{})
+}
+
+// MarshalJSON implements json.Marshaler, writing the node with the default
+// JSONOptions. Nil is written as null.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	return n.ToJSON(JSONOptions{})
+}
+
+// ToJSON returns the table as JSON. Strings, bools and numbers become JSON
+// values of the same type; outside JSONTagged, strings that aren't valid
+// UTF-8 have the invalid bytes replaced with U+FFFD. Outside JSONTagged, NaN and infinite floats
+// can't be written and give an error. A table that contains itself gives an
+// error wrapping ErrCycle.
+func (t *Table) ToJSON(opts JSONOptions) ([]byte, error) {
+	return NewNode(NodeTypeTable, t).ToJSON(opts)
+}
+
+// ToJSON returns the node's value as JSON, as Table.ToJSON does.
+func (n *Node) ToJSON(opts JSONOptions) ([]byte, error) {
+	e := &jsonEncoder{opts: opts, active: make(map[*Table]bool)}
+	if err := e.value(nil, n); err != nil {
+		return nil, err
+	}
+	if opts.Indent == "" {
+		return e.b, nil
+	}
+	var b bytes.Buffer
+	if err := json.Indent(&b, e.b, "", opts.Indent); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// FromJSON reads a JSON object or array and converts it to a table. Arrays
+// become sequences, so JSON's index 0 becomes Lua's index 1, and objects
+// become tables with string keys in the order they appear; a repeated key
+// keeps the last value. Numbers written with a fraction or exponent become
+// floats and others integers, except integers too large for an int64, which
+// become floats. null becomes nil, which leaves the entry out.
+func FromJSON(r io.Reader) (*Table, error) {
+	return fromJSON(r, false)
+}
+
+// FromJSONTagged reads the JSONTagged encoding written by ToJSON and
+// converts it back to the table it was written from, with its key types,
+// floats and entry order.
+func FromJSONTagged(r io.Reader) (*Table, error) {
+	return fromJSON(r, true)
+}
+
+func fromJSON(r io.Reader, tagged bool) (*Table, error) {
+	d := &jsonDecoder{dec: json.NewDecoder(r), tagged: tagged}
+	d.dec.UseNumber()
+	n, err := d.value(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	if n.GetType() != NodeTypeTable {
+		return nil, notTableError(nil, n)
+	}
+	return n.GetTable(), nil
+}
+
+type jsonDecoder struct {
+	dec    *json.Decoder
+	tagged bool // Whether the input is in the JSONTagged encoding
+}
+
+// value reads the next JSON value. path is its path, for errors.
+func (d *jsonDecoder) value(path Path) (*Node, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, WithPath(err, path...)
+	}
+	switch v := tok.(type) {
+	case nil:
+		return NodeOf(Nil{}), nil
+	case string:
+		return NewNode(NodeTypeString, v), nil
+	case bool:
+		return NewNode(NodeTypeBool, v), nil
+	case json.Number:
+		num, err := ParseNumber(string(v))
+		if err != nil {
+			return nil, WithPath(err, path...)
+		}
+		return NodeOf(num), nil
+	case json.Delim:
+		switch {
+		case v == '{' && d.tagged:
+			return d.taggedValue(path)
+		case v == '{':
+			return d.object(path)
+		case v == '[' && !d.tagged:
+			return d.array(path)
+		}
+	}
+	return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("unexpected %v", tok)}
+}
+
+func (d *jsonDecoder) array(path Path) (*Node, error) {
+	t := NewTable()
+	for i := 1; d.dec.More(); i++ {
+		k := intKey(i)
+		n, err := d.value(path.child(k))
+		if err != nil {
+			return nil, err
+		}
+		t.Set(k, n)
+	}
+	return NodeOf(t), d.expect(path, ']')
+}
+
+func (d *jsonDecoder) object(path Path) (*Node, error) {
+	t := NewTable()
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, WithPath(err, path...)
+		}
+		k := NewNode(NodeTypeString, tok.(string))
+		n, err := d.value(path.child(k))
+		if err != nil {
+			return nil, err
+		}
+		t.Set(k, n)
+	}
+	return NodeOf(t), d.expect(path, '}')
+}
+
+// taggedValue reads the rest of a {"table": ...}, {"float": ...} or
+// {"bytes": ...} object.
+func (d *jsonDecoder) taggedValue(path Path) (*Node, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, WithPath(err, path...)
+	}
+	var n *Node
+	switch tok {
+	case "table":
+		n, err = d.taggedTable(path)
+	case "float":
+		n, err = d.taggedFloat(path)
+	case "bytes":
+		n, err = d.taggedBytes(path)
+	default:
+		err = &PathError{Path: path.clone(), Err: fmt.Errorf("unexpected tag %v", tok)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, d.expect(path, '}')
+}
+
+func (d *jsonDecoder) taggedTable(path Path) (*Node, error) {
+	if err := d.expect(path, '['); err != nil {
+		return nil, err
+	}
+	t := NewTable()
+	for d.dec.More() {
+		if err := d.expect(path, '['); err != nil {
+			return nil, err
+		}
+		k, err := d.value(path)
+		if err != nil {
+			return nil, err
+		}
+		if k.IsNil() || (k.GetType() == NodeTypeNumber && math.IsNaN(k.GetFloat64())) {
+			return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("invalid key %v", k)}
+		}
+		v, err := d.value(path.child(k))
+		if err != nil {
+			return nil, err
+		}
+		if err := d.expect(path, ']'); err != nil {
+			return nil, err
+		}
+		t.Set(k, v)
+	}
+	return NodeOf(t), d.expect(path, ']')
+}
+
+func (d *jsonDecoder) taggedFloat(path Path) (*Node, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, WithPath(err, path...)
+	}
+	switch v := tok.(type) {
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err == nil || errors.Is(err, strconv.ErrRange) {
+			return NodeOf(FloatNumber(f)), nil
+		}
+	case string:
+		switch v {
+		case "inf":
+			return NodeOf(FloatNumber(math.Inf(1))), nil
+		case "-inf":
+			return NodeOf(FloatNumber(math.Inf(-1))), nil
+		case "nan":
+			return NodeOf(FloatNumber(math.NaN())), nil
+		}
+	}
+	return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("invalid float %v", tok)}
+}
+
+func (d *jsonDecoder) taggedBytes(path Path) (*Node, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, WithPath(err, path...)
+	}
+	if v, ok := tok.(string); ok {
+		if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+			return NewNode(NodeTypeString, string(b)), nil
+		}
+	}
+	return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("invalid bytes %v", tok)}
+}
+
+// expect reads the next token, which must be delim.
+func (d *jsonDecoder) expect(path Path, delim json.Delim) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return WithPath(err, path...)
+	}
+	if tok != delim {
+		return &PathError{Path: path.clone(), Err: fmt.Errorf("expected %v, got %v", delim, tok)}
+	}
+	return nil
+}
+
+type jsonEncoder struct {
+	opts   JSONOptions
+	b      []byte
+	active map[*Table]bool // Tables being written, to detect cycles
+}
+
+func (e *jsonEncoder) value(path Path, n *Node) error {
+	switch n.GetType() {
+	case NodeTypeString, NodeTypeIdentifier:
+		s := n.GetString()
+		if e.opts.Mode != JSONTagged || utf8.ValidString(s) {
+			e.b = appendQuotedJSON(e.b, s)
+			break
+		}
+		e.b = append(e.b, `{"bytes":"`...)
+		e.b = append(e.b, base64.StdEncoding.EncodeToString([]byte(s))...)
+		e.b = append(e.b, `"}`...)
+	case NodeTypeBool:
+		e.b = strconv.AppendBool(e.b, n.GetBool())
+	case NodeTypeNumber:
+		return e.number(path, n.GetNumber())
+	case NodeTypeTable:
+		return e.table(path, n.GetTable())
+	default:
+		e.b = append(e.b, "null"...)
+	}
+	return nil
+}
+
+func (e *jsonEncoder) number(path Path, num Number) error {
+	if num.IsInteger() {
+		i, _ := num.Int64()
+		e.b = strconv.AppendInt(e.b, i, 10)
+		return nil
+	}
+	f := num.Float64()
+	if e.opts.Mode != JSONTagged {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return &PathError{Path: path.clone(), Err: errors.New("can't write " + strconv.FormatFloat(f, 'g', -1, 64) + " as JSON")}
+		}
+		e.b = strconv.AppendFloat(e.b, f, 'g', -1, 64)
+		return nil
+	}
+	e.b = append(e.b, `{"float":`...)
+	switch {
+	case math.IsNaN(f):
+		e.b = append(e.b, `"nan"`...)
+	case math.IsInf(f, 1):
+		e.b = append(e.b, `"inf"`...)
+	case math.IsInf(f, -1):
+		e.b = append(e.b, `"-inf"`...)
+	default:
+		e.b = strconv.AppendFloat(e.b, f, 'g', -1, 64)
+	}
+	e.b = append(e.b, '}')
+	return nil
+}
+
+func (e *jsonEncoder) table(path Path, t *Table) error {
+	if e.active[t] {
+		return &PathError{Path: path.clone(), Err: ErrCycle}
+	}
+	e.active[t] = true
+	defer delete(e.active, t)
+
+	switch seqLen := t.SeqLen(); {
+	case e.opts.Mode == JSONTagged:
+		return e.tagged(path, t)
+	case e.opts.Mode == JSONArrays && seqLen > 0 && seqLen == t.Len():
+		e.b = append(e.b, '[')
+		for i := 1; i <= seqLen; i++ {
+			if i > 1 {
+				e.b = append(e.b, ',')
+			}
+			k := intKey(i)
+			if err := e.value(path.child(k), t.Get(k)); err != nil {
+				return err
+			}
+		}
+		e.b = append(e.b, ']')
+		return nil
+	}
+
+	// Keys that stringify the same are written once, in the position of the
+	// first, with the value of the last.
+	last := make(map[string]*tableEntry, len(t.entries))
+	for _, entry := range t.entries {
+		last[keyString(entry.key)] = entry
+	}
+	e.b = append(e.b, '{')
+	first := true
+	for _, entry := range t.entries {
+		s := keyString(entry.key)
+		latest, ok := last[s]
+		if !ok {
+			continue
+		}
+		delete(last, s)
+		if !first {
+			e.b = append(e.b, ',')
+		}
+		first = false
+		e.b = appendQuotedJSON(e.b, s)
+		e.b = append(e.b, ':')
+		if err := e.value(path.child(latest.key), latest.value); err != nil {
+			return err
+		}
+	}
+	e.b = append(e.b, '}')
+	return nil
+}
+
+func (e *jsonEncoder) tagged(path Path, t *Table) error {
+	e.b = append(e.b, `{"table":[`...)
+	for i, entry := range t.entries {
+		if i > 0 {
+			e.b = append(e.b, ',')
+		}
+		e.b = append(e.b, '[')
+		if err := e.value(path, entry.key); err != nil {
+			return err
+		}
+		e.b = append(e.b, ',')
+		if err := e.value(path.child(entry.key), entry.value); err != nil {
+			return err
+		}
+		e.b = append(e.b, ']')
+	}
+	e.b = append(e.b, "]}"...)
+	return nil
+}
+
+// appendQuotedJSON appends s to b as a JSON string. Invalid UTF-8 is
+// replaced with U+FFFD and U+2028 and U+2029 are escaped, as encoding/json
+// does.
+func appendQuotedJSON(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"', c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c < ' ':
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, "\uFFFD"...)
+		case r == '\u2028', r == '\u2029':
+			// Valid JSON but not valid JavaScript
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		default:
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,188 @@
+package wowlua
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tab := Map(
+		"seq", Seq("a", 2, 2.5),
+		"mixed", Map(1, "one", "name", "x\n"),
+		"flags", Map(true, "yes"),
+		"empty", Map(),
+	)
+	tests := []struct {
+		name     string
+		opts     JSONOptions
+		expected string
+	}{
+		{"arrays", JSONOptions{}, `{"seq":["a",2,2.5],"mixed":{"1":"one","name":"x\n"},"flags":{"true":"yes"},"empty":{}}`},
+		{"objects", JSONOptions{Mode: JSONObjects}, `{"seq":{"1":"a","2":2,"3":2.5},"mixed":{"1":"one","name":"x\n"},"flags":{"true":"yes"},"empty":{}}`},
+		{"tagged", JSONOptions{Mode: JSONTagged}, `{"table":[["seq",{"table":[[1,"a"],[2,2],[3,{"float":2.5}]]}],` +
+			`["mixed",{"table":[[1,"one"],["name","x\n"]]}],["flags",{"table":[[true,"yes"]]}],["empty",{"table":[]}]]}`},
+		{"indented", JSONOptions{Indent: "  "}, "{\n  \"seq\": [\n    \"a\",\n    2,\n    2.5\n  ],\n  \"mixed\": {\n    \"1\": \"one\",\n" +
+			"    \"name\": \"x\\n\"\n  },\n  \"flags\": {\n    \"true\": \"yes\"\n  },\n  \"empty\": {}\n}"},
+	}
+	for _, test := range tests {
+		b, err := tab.ToJSON(test.opts)
+		if err != nil {
+			t.Errorf("Unexpected %s error: %v", test.name, err)
+			continue
+		}
+		if string(b) != test.expected {
+			t.Errorf("Expected %s JSON\n%s\ngot\n%s", test.name, test.expected, b)
+		}
+	}
+}
+
+func TestToJSONKeyCollision(t *testing.T) {
+	tab := Map(1, "number", "1", "string", "2", "other")
+	b, err := tab.ToJSON(JSONOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{"1":"string","2":"other"}`; string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	tab := Map("inf", Map("x", math.Inf(1)))
+	_, err := tab.ToJSON(JSONOptions{})
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) || pathErr.Path.String() != "inf/x" {
+		t.Errorf("Expected an error at inf/x, got %v", err)
+	}
+	b, err := tab.ToJSON(JSONOptions{Mode: JSONTagged})
+	if expected := `{"table":[["inf",{"table":[["x",{"float":"inf"}]]}]]}`; err != nil || string(b) != expected {
+		t.Errorf("Expected %s, got %s, %v", expected, b, err)
+	}
+
+	loop := Map("name", "loop")
+	loop.Set(NewNode(NodeTypeString, "self"), NewNode(NodeTypeTable, loop))
+	if _, err := loop.ToJSON(JSONOptions{}); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle, got %v", err)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	v := struct {
+		Config *Table
+		Scale  *Node
+	}{Map("alts", Seq("Volne", "Harbinger")), NodeOf(FloatNumber(1.5))}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{"Config":{"alts":["Volne","Harbinger"]},"Scale":1.5}`; string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tab, err := FromJSON(strings.NewReader(`{"seq": ["a", 2, 2.5, null, 1e2], "name": "x", "big": 12345678901234567890, "skip": null, "name": "y"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Map("seq", Map(1, "a", 2, 2, 3, 2.5, 5, 100.0), "name", "y", "big", 12345678901234567890.0)
+	if !tab.Equals(expected) {
+		t.Errorf("Expected %v, got %v", expected, tab)
+	}
+	if n := tab.GetByString("seq").GetTable().GetIndexed(2); !n.IsInteger() {
+		t.Errorf("Expected 2 to stay an integer")
+	}
+	if n := tab.GetByString("seq").GetTable().GetIndexed(5); n.IsInteger() {
+		t.Errorf("Expected 1e2 to be a float")
+	}
+	if keys := tab.Keys(); keys[0].GetString() != "seq" || keys[1].GetString() != "name" || keys[2].GetString() != "big" {
+		t.Errorf("Expected keys in document order, got %v", keys)
+	}
+}
+
+func TestFromJSONTagged(t *testing.T) {
+	tab := Map(
+		"seq", Seq("a", 2, 2.0),
+		"mixed", Map(1, "one", "name", "x", true, false, 1.5, "float key"),
+		"limits", Seq(math.Inf(1), math.Inf(-1)),
+		"packed", "\xff\x00ok",
+		"\xfe", "binary key",
+	)
+	tab.Set(NewNode(NodeTypeTable, Map("table", "key")), NewNode(NodeTypeString, "table key"))
+	b, err := tab.ToJSON(JSONOptions{Mode: JSONTagged})
+	if err != nil {
+		t.Fatalf("Unexpected error encoding: %v", err)
+	}
+	back, err := FromJSONTagged(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Unexpected error decoding: %v", err)
+	}
+	again, err := back.ToJSON(JSONOptions{Mode: JSONTagged})
+	if err != nil {
+		t.Fatalf("Unexpected error encoding again: %v", err)
+	}
+	if string(again) != string(b) {
+		t.Errorf("Expected round trip to give\n%s\ngot\n%s", b, again)
+	}
+	if n := back.GetByString("seq").GetTable().GetIndexed(3); n.IsInteger() {
+		t.Errorf("Expected 2.0 to stay a float")
+	}
+	if packed := back.GetByString("packed").GetString(); packed != "\xff\x00ok" {
+		t.Errorf("Expected non-UTF-8 string to survive, got %q", packed)
+	}
+	if key := back.GetByString("\xfe").GetString(); key != "binary key" {
+		t.Errorf("Expected non-UTF-8 key to survive, got %q", key)
+	}
+	if !bytes.Contains(b, []byte(`["packed",{"bytes":"/wBvaw=="}]`)) {
+		t.Errorf("Expected a non-UTF-8 string as bytes, got %s", b)
+	}
+}
+
+func TestFromJSONPlainTableKey(t *testing.T) {
+	tab, err := FromJSON(strings.NewReader(`{"table": [["a", 1]]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Map("table", Seq(Seq("a", 1)))
+	if !tab.Equals(expected) {
+		t.Errorf("Expected %v, got %v", expected, tab)
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		tagged bool
+		path   string
+	}{
+		{"scalar root", `"text"`, false, ""},
+		{"trailing data", `{} {}`, false, ""},
+		{"syntax", `{"a": [1, }`, false, ""},
+		{"untagged", `{"a": 1}`, true, ""},
+		{"bad tag", `{"table": [["a", {"int": 1}]]}`, true, "a"},
+		{"bad float", `{"table": [["a", {"float": "big"}]]}`, true, "a"},
+		{"bad bytes", `{"table": [["a", {"bytes": "!"}]]}`, true, "a"},
+		{"nil key", `{"table": [[null, 1]]}`, true, ""},
+	}
+	for _, test := range tests {
+		var err error
+		if test.tagged {
+			_, err = FromJSONTagged(strings.NewReader(test.input))
+		} else {
+			_, err = FromJSON(strings.NewReader(test.input))
+		}
+		if err == nil {
+			t.Errorf("Expected %s error", test.name)
+			continue
+		}
+		var pathErr *PathError
+		if test.path != "" && (!errors.As(err, &pathErr) || pathErr.Path.String() != test.path) {
+			t.Errorf("Expected %s error at %s, got %v", test.name, test.path, err)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package wowlua
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// LuaMarshaler is implemented by types that can convert themselves to a
+// node. A nil node is nil.
+type LuaMarshaler interface {
+	MarshalLua() (*Node, error)
+}
+
+var (
+	luaMarshalerType  = reflect.TypeOf((*LuaMarshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Marshal returns SavedVariables text for v, which must be a struct or a
+// map with string keys: each field or entry becomes a top-level assignment.
+// Values are converted as NewTableFrom describes and written as Encode
+// does.
+func Marshal(v interface{}) ([]byte, error) {
+	t, err := NewTableFrom(v)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := Encode(&b, t); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// NewTableFrom converts a struct, map, slice or array, or a pointer to one,
+// into a table. It's the inverse of Table.Decode:
+//
+// Struct fields become entries keyed by the field name, or the name given by
+// a `lua:"key"` tag. A tag of "-" skips the field and the omitempty option
+// skips it when it's false, 0, "", a nil pointer or interface, or an empty
+// map, slice or array. Fields of untagged embedded structs are treated as
+// fields of the outer struct.
+//
+// Slices and arrays become sequences, so Go's index 0 becomes Lua's index 1.
+// Maps become tables keyed by their keys, which must be strings, integers,
+// floats or bools. Map entries are added in sorted key order.
+//
+// Go numbers become integers or floats according to their type, and *Node,
+// *Table and Value values are used as they are.
+//
+// Types that implement LuaMarshaler convert themselves, as map keys too.
+// Otherwise types that implement encoding.TextMarshaler become strings.
+// Pointer methods are used only for values that are addressable, such as
+// fields of a struct passed by pointer.
+//
+// Nil pointers, interfaces, maps and slices are nil, so struct fields and
+// map entries holding them are left out.
+//
+// Errors are *PathErrors naming the path of the value that couldn't be
+// converted.
+func NewTableFrom(v interface{}) (*Table, error) {
+	e := &marshaler{active: make(map[marshalRef]bool)}
+	n, err := e.node(nil, reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	if n.GetType() != NodeTypeTable {
+		return nil, &PathError{Err: ErrNotTable, Expected: NodeTypeTable, Actual: n.GetType()}
+	}
+	return n.GetTable(), nil
+}
+
+type marshaler struct {
+	active map[marshalRef]bool // Pointers, maps and slices being converted, to detect cycles
+}
+
+type marshalRef struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// node converts rv to a node. Nil values give a Nil node.
+func (e *marshaler) node(path Path, rv reflect.Value) (*Node, error) {
+	if !rv.IsValid() {
+		return NodeOf(Nil{}), nil
+	}
+	if n, ok, err := e.custom(path, rv); ok {
+		return n, err
+	}
+	if rv.CanInterface() {
+		switch v := rv.Interface().(type) {
+		case *Node:
+			if v == nil {
+				return NodeOf(Nil{}), nil
+			}
+			return v, nil
+		case *Table:
+			if v == nil {
+				return NodeOf(Nil{}), nil
+			}
+			return NewNode(NodeTypeTable, v), nil
+		case *ImmutableTable:
+			if v == nil {
+				return NodeOf(Nil{}), nil
+			}
+			return NewNode(NodeTypeTable, v.Thaw()), nil
+		case Value:
+			if rv.Kind() != reflect.Ptr || !rv.IsNil() {
+				return NodeOf(v), nil
+			}
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return NodeOf(Nil{}), nil
+		}
+		if rv.Kind() == reflect.Interface {
+			return e.node(path, rv.Elem())
+		}
+		ref := marshalRef{rv.Pointer(), rv.Type()}
+		if e.active[ref] {
+			return nil, &PathError{Path: path.clone(), Err: ErrCycle}
+		}
+		e.active[ref] = true
+		defer delete(e.active, ref)
+		return e.node(path, rv.Elem())
+	case reflect.String:
+		return NewNode(NodeTypeString, rv.String()), nil
+	case reflect.Bool:
+		return NewNode(NodeTypeBool, rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewNode(NodeTypeNumber, IntNumber(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, &PathError{Path: path.clone(), Err: ErrOverflow}
+		}
+		return NewNode(NodeTypeNumber, IntNumber(int64(u))), nil
+	case reflect.Float32, reflect.Float64:
+		return NewNode(NodeTypeNumber, FloatNumber(rv.Float())), nil
+	case reflect.Struct:
+		return e.structTable(path, rv)
+	case reflect.Map:
+		if rv.IsNil() {
+			return NodeOf(Nil{}), nil
+		}
+		ref := marshalRef{rv.Pointer(), rv.Type()}
+		if e.active[ref] {
+			return nil, &PathError{Path: path.clone(), Err: ErrCycle}
+		}
+		e.active[ref] = true
+		defer delete(e.active, ref)
+		return e.mapTable(path, rv)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return NodeOf(Nil{}), nil
+		}
+		if rv.Len() > 0 {
+			ref := marshalRef{rv.Pointer(), rv.Type()}
+			if e.active[ref] {
+				return nil, &PathError{Path: path.clone(), Err: ErrCycle}
+			}
+			e.active[ref] = true
+			defer delete(e.active, ref)
+		}
+		fallthrough
+	case reflect.Array:
+		t := NewTable()
+		for i := 0; i < rv.Len(); i++ {
+			k := intKey(i + 1)
+			n, err := e.node(path.child(k), rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			t.Set(k, n)
+		}
+		return NewNode(NodeTypeTable, t), nil
+	}
+	return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("can't marshal %v", rv.Type())}
+}
+
+// custom converts rv with its LuaMarshaler or encoding.TextMarshaler
+// method and returns whether it had one.
+func (e *marshaler) custom(path Path, rv reflect.Value) (*Node, bool, error) {
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() {
+		if pt := reflect.PtrTo(rv.Type()); pt.Implements(luaMarshalerType) || pt.Implements(textMarshalerType) {
+			rv = rv.Addr()
+		}
+	}
+	if !rv.CanInterface() {
+		return nil, false, nil
+	}
+	switch m := rv.Interface().(type) {
+	case LuaMarshaler:
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return NodeOf(Nil{}), true, nil
+		}
+		n, err := m.MarshalLua()
+		if err != nil {
+			return nil, true, WithPath(err, path...)
+		}
+		if n == nil {
+			n = NodeOf(Nil{})
+		}
+		return n, true, nil
+	case encoding.TextMarshaler:
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return NodeOf(Nil{}), true, nil
+		}
+		b, err := m.MarshalText()
+		if err != nil {
+			return nil, true, WithPath(err, path...)
+		}
+		return NewNode(NodeTypeString, string(b)), true, nil
+	}
+	return nil, false, nil
+}
+
+func (e *marshaler) structTable(path Path, rv reflect.Value) (*Node, error) {
+	t := NewTable()
+	for _, f := range structFields(rv.Type()) {
+		fv := rv.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		k := NewNode(NodeTypeString, f.name)
+		n, err := e.node(path.child(k), fv)
+		if err != nil {
+			return nil, err
+		}
+		t.Set(k, n)
+	}
+	return NewNode(NodeTypeTable, t), nil
+}
+
+func (e *marshaler) mapTable(path Path, rv reflect.Value) (*Node, error) {
+	type entry struct {
+		key   *Node
+		value reflect.Value
+	}
+	entries := make([]entry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k, err := e.key(path, iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{k, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return canonicalLess(entries[i].key, entries[j].key) })
+
+	t := NewTable()
+	for _, entry := range entries {
+		n, err := e.node(path.child(entry.key), entry.value)
+		if err != nil {
+			return nil, err
+		}
+		t.Set(entry.key, n)
+	}
+	return NewNode(NodeTypeTable, t), nil
+}
+
+// key converts a map key.
+func (e *marshaler) key(path Path, rv reflect.Value) (*Node, error) {
+	if rv.Kind() == reflect.Interface && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Type() == tableType && rv.CanInterface() && !rv.IsNil() {
+		return NewNode(NodeTypeTable, rv.Interface().(*Table)), nil
+	}
+	if k, ok, err := e.custom(path, rv); ok {
+		if err != nil {
+			return nil, err
+		}
+		switch k.GetType() {
+		case NodeTypeString, NodeTypeBool:
+			return k, nil
+		case NodeTypeNumber:
+			if !math.IsNaN(k.GetFloat64()) {
+				return k, nil
+			}
+		}
+		return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("can't use %v from %v as a key", k.GetType(), rv.Type())}
+	}
+	switch rv.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		k, err := e.node(path, rv)
+		if err != nil {
+			return nil, err
+		}
+		if k.GetType() == NodeTypeNumber && math.IsNaN(k.GetFloat64()) {
+			return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("can't use NaN as a key")}
+		}
+		return k, nil
+	}
+	return nil, &PathError{Path: path.clone(), Err: fmt.Errorf("can't use %v as a key", rv.Type())}
+}
+
+// isEmptyValue reports whether a value is empty for omitempty.
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	}
+	return false
+}
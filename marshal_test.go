@@ -0,0 +1,178 @@
+package wowlua
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type addonConfig struct {
+	Scale    float64           `lua:"scale"`
+	Channels []string          `lua:"channels"`
+	Ranks    map[int]string    `lua:"ranks"`
+	Colors   map[string][3]int `lua:"colors,omitempty"`
+	Note     string            `lua:"note,omitempty"`
+	Parent   *addonConfig      `lua:"parent,omitempty"`
+	Secret   string            `lua:"-"`
+}
+
+func TestMarshal(t *testing.T) {
+	saved := struct {
+		Config addonConfig `lua:"MyAddon_Config"`
+	}{addonConfig{
+		Scale:    1.5,
+		Channels: []string{"GUILD", "RAID"},
+		Ranks:    map[int]string{2: "Officer", 1: "Guild Master"},
+		Secret:   "hunter2",
+	}}
+	b, err := Marshal(saved)
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling: %v", err)
+	}
+	expected := `MyAddon_Config = {
+	["scale"] = 1.5,
+	["channels"] = {
+		"GUILD", -- [1]
+		"RAID", -- [2]
+	},
+	["ranks"] = {
+		"Guild Master", -- [1]
+		"Officer", -- [2]
+	},
+}
+`
+	if string(b) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b)
+	}
+
+	unmarshaled := saved
+	unmarshaled.Config = addonConfig{}
+	if err := Unmarshal(b, &unmarshaled); err != nil {
+		t.Fatalf("Unexpected error unmarshaling: %v", err)
+	}
+	saved.Config.Secret = ""
+	if !reflect.DeepEqual(unmarshaled, saved) {
+		t.Errorf("Expected round trip to give %+v, got %+v", saved, unmarshaled)
+	}
+}
+
+func TestNewTableFromErrors(t *testing.T) {
+	cyclic := &addonConfig{}
+	cyclic.Parent = cyclic
+	cyclic_slice := []interface{}{"a", nil}
+	cyclic_slice[1] = cyclic_slice
+	tests := []struct {
+		value    interface{}
+		path     string
+		expected error
+	}{
+		{cyclic, "parent", ErrCycle},
+		{map[string]interface{}{"list": cyclic_slice}, "list[2]", ErrCycle},
+		{map[string]uint64{"big": 1 << 63}, "big", ErrOverflow},
+		{"not a table", "", ErrNotTable},
+	}
+	for _, test := range tests {
+		_, err := NewTableFrom(test.value)
+		var path_err *PathError
+		if !errors.As(err, &path_err) || !errors.Is(err, test.expected) {
+			t.Errorf("Expected %v converting %T, got %v", test.expected, test.value, err)
+			continue
+		}
+		if path_err.Path.String() != test.path {
+			t.Errorf("Expected error at %q, got %q", test.path, path_err.Path)
+		}
+	}
+}
+
+func TestNewTableFromLargeIntegerKeys(t *testing.T) {
+	ids := map[int64]string{1<<53 + 3: "d", 1 << 53: "a", 1<<53 + 2: "c", 1<<53 + 1: "b"}
+	for run := 0; run < 20; run++ {
+		tab, err := NewTableFrom(ids)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i, k := range tab.Keys() {
+			if id, _ := k.GetNumber().Int64(); id != 1<<53+int64(i) {
+				t.Fatalf("Expected key %d at position %d, got %v", int64(1<<53+i), i, k)
+			}
+		}
+	}
+}
+
+// money is stored in Lua as a number of copper.
+type money struct {
+	Gold, Silver, Copper int
+}
+
+func (m money) MarshalLua() (*Node, error) {
+	return NodeOf(Int(int64(m.Gold*10000 + m.Silver*100 + m.Copper))), nil
+}
+
+func (m *money) UnmarshalLua(n *Node) error {
+	copper, err := n.AsInt()
+	if err != nil {
+		return err
+	}
+	if copper < 0 {
+		return errors.New("negative money")
+	}
+	*m = money{int(copper / 10000), int(copper / 100 % 100), int(copper % 100)}
+	return nil
+}
+
+type itemID struct {
+	id int
+}
+
+func (i itemID) MarshalLua() (*Node, error) {
+	return NodeOf(Int(int64(i.id))), nil
+}
+
+func (i *itemID) UnmarshalLua(n *Node) error {
+	id, err := n.AsInt()
+	i.id = int(id)
+	return err
+}
+
+type ledger struct {
+	Balance money            `lua:"balance"`
+	Prices  map[itemID]money `lua:"prices"`
+	Updated time.Time        `lua:"updated"`
+}
+
+func TestCustomMarshalers(t *testing.T) {
+	in := ledger{
+		Balance: money{12, 34, 56},
+		Prices:  map[itemID]money{{19019}: {Gold: 5}},
+		Updated: time.Date(2014, 11, 1, 7, 14, 56, 0, time.UTC),
+	}
+	tab, err := NewTableFrom(&in)
+	if err != nil {
+		t.Fatalf("Unexpected error converting: %v", err)
+	}
+	if balance, _ := tab.AsInt(NewPath("balance")...); balance != 123456 {
+		t.Errorf("Expected balance 123456, got %d", balance)
+	}
+	if price, _ := tab.AsInt(NewPath("prices", 19019)...); price != 50000 {
+		t.Errorf("Expected price 50000, got %d", price)
+	}
+	if updated, _ := tab.AsString(NewPath("updated")...); updated != "2014-11-01T07:14:56Z" {
+		t.Errorf("Expected updated 2014-11-01T07:14:56Z, got %q", updated)
+	}
+
+	var out ledger
+	if err := tab.Decode(&out); err != nil {
+		t.Fatalf("Unexpected error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Expected round trip to give %+v, got %+v", in, out)
+	}
+
+	tab.Set(NewNode(NodeTypeString, "balance"), NodeOf(Int(-1)))
+	err = tab.Decode(&out)
+	var path_err *PathError
+	if !errors.As(err, &path_err) || path_err.Path.String() != "balance" {
+		t.Errorf("Expected error at balance, got %v", err)
+	}
+}
@@ -3,23 +3,6 @@ package wowlua
 import (
 	"fmt"
 	"math"
-	"reflect"
-	"strconv"
-)
-
-const (
-	// NodeTypeString is a node containing a string
-	NodeTypeString = iota
-	// NodeTypeIdentifier is a node containing an identifier
-	NodeTypeIdentifier
-	// NodeTypeNumber is a node containing a number
-	NodeTypeNumber
-	// NodeTypeBool is a node containing a bool
-	NodeTypeBool
-	// NodeTypeTable is a node containing a table
-	NodeTypeTable
-	// NodeTypeTableEntry is a node containing a table entry
-	NodeTypeTableEntry
 )
 
 var (
@@ -27,22 +10,73 @@ var (
 	NaN = math.NaN()
 )
 
-// Node contains a parsed value. This value can be a scalar or a table
+// Node contains a parsed value. This value can be a scalar or a table. Node
+// wraps a Value; use Value() to get at it with a type switch.
 type Node struct {
-	nType int
-	value interface{}
+	nType NodeType
+	value Value
 }
 
 // NewNode creates a node of the given type with the provided value. It assumes
-// the specified type is appropriate for the value.
-func NewNode(nType int, v interface{}) *Node {
+// the specified type is appropriate for the value. The value may be a Value or
+// the equivalent Go type: a string, bool or *Table. Number nodes may be given a
+// Number, a Go integer or float, or a numeric string.
+func NewNode(nType NodeType, v interface{}) *Node {
+	var value Value
+	switch nType {
+	case NodeTypeNumber:
+		value = toNumber(v)
+	case NodeTypeString, NodeTypeIdentifier:
+		switch sv := v.(type) {
+		case string:
+			value = String(sv)
+		case String:
+			value = sv
+		}
+	case NodeTypeBool:
+		switch bv := v.(type) {
+		case bool:
+			value = Bool(bv)
+		case Bool:
+			value = bv
+		}
+	case NodeTypeNil:
+		value = Nil{}
+	default:
+		value, _ = v.(Value)
+	}
 	n := &Node{
 		nType: nType,
-		value: v,
+		value: value,
 	}
 	return n
 }
 
+// NodeOf creates a node holding the provided value. A nil Value gives a node
+// holding Nil. An *ImmutableTable is thawed, so the node holds a *Table as
+// every table node does.
+func NodeOf(v Value) *Node {
+	switch tv := v.(type) {
+	case nil:
+		v = Nil{}
+	case *ImmutableTable:
+		if tv == nil {
+			v = Nil{}
+		} else {
+			v = tv.Thaw()
+		}
+	}
+	return &Node{nType: v.Type(), value: v}
+}
+
+// Value returns the value the node holds. Identifier nodes give a String.
+func (n *Node) Value() Value {
+	if n.value == nil {
+		return Nil{}
+	}
+	return n.value
+}
+
 // String returns a string representation of the value
 func (n *Node) String() string {
 	switch n.nType {
@@ -53,25 +87,24 @@ func (n *Node) String() string {
 	case NodeTypeTable:
 		return fmt.Sprint("TABLE: ", n.GetTable())
 	case NodeTypeNumber:
-		return fmt.Sprint("NUMBER: ", n.GetFloat64())
+		return "NUMBER: " + n.GetNumber().String()
 	case NodeTypeBool:
 		return fmt.Sprint("BOOL: ", n.GetBool())
-	case NodeTypeTableEntry:
-		if v, ok := n.value.(*tableEntry); ok {
-			return fmt.Sprint("TABLEENTRY: ", v)
-		}
-		return ">> NodeTypeTableEntry failed type assertion to *tableEntry <<" + reflect.ValueOf(n.value).String()
+	case NodeTypeNil:
+		return "NIL"
+	case nodeTypeTableEntry:
+		return fmt.Sprint("TABLEENTRY: ", n.value)
 	}
 	return "NOPE (" + fmt.Sprint(n.nType) + ")"
 }
 
 // Equals returns whether this node is equal to another. For this to be true
-// the types and values must match. Not all types support equality testing.
+// the types and values must match.
 func (n *Node) Equals(o *Node) bool {
 	if n == o {
 		return true
 	}
-	if n.nType != o.nType {
+	if n == nil || o == nil || n.nType != o.nType {
 		return false
 	}
 	switch n.nType {
@@ -80,30 +113,65 @@ func (n *Node) Equals(o *Node) bool {
 	case NodeTypeIdentifier:
 		return n.GetString() == o.GetString()
 	case NodeTypeNumber:
-		return n.GetFloat64() == o.GetFloat64()
+		return n.GetNumber().Equals(o.GetNumber())
+	case NodeTypeBool:
+		return n.GetBool() == o.GetBool()
+	case NodeTypeTable:
+		return n.GetTable().Equals(o.GetTable())
+	case NodeTypeNil:
+		return true
 	default:
 		logger.Errorf("Unhandled type comparison: %v", n.nType)
 	}
 	return false
 }
 
+// DeepCopy returns a copy of the node. Tables are copied recursively so the
+// copy shares nothing with the original.
+func (n *Node) DeepCopy() *Node {
+	if n == nil {
+		return nil
+	}
+	if t := n.GetTable(); t != nil {
+		return NewNode(NodeTypeTable, t.DeepCopy())
+	}
+	return &Node{nType: n.nType, value: n.value}
+}
+
 // GetType returns the type the node holds
-func (n *Node) GetType() int {
+func (n *Node) GetType() NodeType {
 	return n.nType
 }
 
+// IsNil returns whether the node holds nil.
+func (n *Node) IsNil() bool {
+	return n.nType == NodeTypeNil
+}
+
 // GetString returns the underlying string value of the node if it is a string
 // or identifier type and empty string if not
 func (n *Node) GetString() string {
 	if n.nType != NodeTypeString && n.nType != NodeTypeIdentifier {
 		return ""
 	}
-	if s, ok := n.value.(string); ok {
-		return s
+	if s, ok := n.value.(String); ok {
+		return string(s)
 	}
 	return ""
 }
 
+// GetNumber returns the underlying Number if the node is numeric and the zero
+// Number if not
+func (n *Node) GetNumber() Number {
+	if n.nType != NodeTypeNumber {
+		return Number{}
+	}
+	if num, ok := n.value.(Number); ok {
+		return num
+	}
+	return Number{}
+}
+
 // GetFloat64 returns the underlying value of the node if it is numeric and NaN
 // if not
 func (n *Node) GetFloat64() float64 {
@@ -111,21 +179,24 @@ func (n *Node) GetFloat64() float64 {
 		logger.Debugf("Node.GetFloat64 called on wrong node type: %v", n)
 		return NaN
 	}
-	if nInt, ok := n.value.(int); ok {
-		return float64(nInt)
-	}
-	if nf64, ok := n.value.(float64); ok {
-		return nf64
-	}
-	if s, ok := n.value.(string); ok {
-		f, err := strconv.ParseFloat(s, 64)
-		if err != nil {
-			logger.Errorf("Error parsing float64 %q", s)
-			return NaN
-		}
-		return f
+	return n.GetNumber().Float64()
+}
+
+// GetInt64 returns the underlying value of the node if it is numeric and has
+// an exact integer representation and 0 if not. Use IsInteger to tell whether
+// the number was an integer to begin with.
+func (n *Node) GetInt64() int64 {
+	if n.nType != NodeTypeNumber {
+		logger.Debugf("Node.GetInt64 called on wrong node type: %v", n)
+		return 0
 	}
-	return NaN
+	i, _ := n.GetNumber().Int64()
+	return i
+}
+
+// IsInteger returns whether the node is a number with an integer subtype.
+func (n *Node) IsInteger() bool {
+	return n.nType == NodeTypeNumber && n.GetNumber().IsInteger()
 }
 
 // GetBool returns the value of the node if it's a bool and false if it is not.
@@ -134,8 +205,8 @@ func (n *Node) GetBool() bool {
 	if n.nType != NodeTypeBool {
 		return false
 	}
-	if b, ok := n.value.(bool); ok {
-		return b
+	if b, ok := n.value.(Bool); ok {
+		return bool(b)
 	}
 	return false
 }
@@ -151,3 +222,26 @@ func (n *Node) GetTable() *Table {
 	}
 	return nil
 }
+
+// toNumber converts the values NewNode accepts for number nodes into a Number.
+func toNumber(v interface{}) Number {
+	switch nv := v.(type) {
+	case Number:
+		return nv
+	case int:
+		return IntNumber(int64(nv))
+	case int64:
+		return IntNumber(nv)
+	case float64:
+		return FloatNumber(nv)
+	case string:
+		num, err := ParseNumber(nv)
+		if err != nil {
+			logger.Errorf("Error parsing number %q", nv)
+			return FloatNumber(NaN)
+		}
+		return num
+	}
+	logger.Errorf("Can't make a number from %T", v)
+	return FloatNumber(NaN)
+}
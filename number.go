@@ -0,0 +1,212 @@
+package wowlua
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidNumber indicates a lexeme couldn't be parsed as a Lua number
+	ErrInvalidNumber = errors.New("invalid number")
+)
+
+// Number is a parsed Lua number. Like Lua 5.3, a number is either an integer
+// or a float. Numbers produced by the tokenizer remember the lexeme they were
+// parsed from so they can be written back exactly as they were read.
+type Number struct {
+	lexeme string
+	isInt  bool
+	i      int64
+	f      float64
+}
+
+// IntNumber creates an integer Number.
+func IntNumber(i int64) Number {
+	return Number{isInt: true, i: i}
+}
+
+// FloatNumber creates a float Number.
+func FloatNumber(f float64) Number {
+	return Number{f: f}
+}
+
+// ParseNumber parses a Lua numeric lexeme. Decimal and hexadecimal integers
+// become integers, anything with a fraction or exponent becomes a float.
+// Decimal integers too large for an int64 become floats and hexadecimal
+// integers wrap around, as they do in Lua 5.3.
+func ParseNumber(s string) (Number, error) {
+	n := Number{lexeme: s}
+	body := s
+	neg := false
+	switch {
+	case strings.HasPrefix(body, "-"):
+		neg = true
+		body = body[1:]
+	case strings.HasPrefix(body, "+"):
+		body = body[1:]
+	}
+	if body == "" {
+		return Number{}, ErrInvalidNumber
+	}
+
+	if strings.HasPrefix(body, "0x") || strings.HasPrefix(body, "0X") {
+		digits := body[2:]
+		if strings.ContainsAny(digits, ".pP") {
+			if !strings.ContainsAny(digits, "pP") {
+				digits += "p0"
+			}
+			f, err := strconv.ParseFloat("0x"+digits, 64)
+			if err != nil {
+				return Number{}, ErrInvalidNumber
+			}
+			if neg {
+				f = -f
+			}
+			n.f = f
+			return n, nil
+		}
+		u, err := strconv.ParseUint(digits, 16, 64)
+		if err != nil {
+			if !errors.Is(err, strconv.ErrRange) {
+				return Number{}, ErrInvalidNumber
+			}
+			// Keep the low 64 bits, as Lua does.
+			if len(digits) > 16 {
+				digits = digits[len(digits)-16:]
+			}
+			if u, err = strconv.ParseUint(digits, 16, 64); err != nil {
+				return Number{}, ErrInvalidNumber
+			}
+		}
+		n.isInt = true
+		n.i = int64(u)
+		if neg {
+			n.i = -n.i
+		}
+		return n, nil
+	}
+
+	if !strings.ContainsAny(body, ".eE") {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err == nil {
+			n.isInt = true
+			n.i = i
+			return n, nil
+		}
+		if !errors.Is(err, strconv.ErrRange) {
+			return Number{}, ErrInvalidNumber
+		}
+	}
+	if body[0] < '0' || body[0] > '9' {
+		if body[0] != '.' {
+			return Number{}, ErrInvalidNumber
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return Number{}, ErrInvalidNumber
+	}
+	n.f = f
+	return n, nil
+}
+
+// IsInteger reports whether the number is an integer.
+func (n Number) IsInteger() bool {
+	return n.isInt
+}
+
+// Int64 returns the number as an int64. For floats, ok is false unless the
+// value has an exact integer representation.
+func (n Number) Int64() (i int64, ok bool) {
+	if n.isInt {
+		return n.i, true
+	}
+	if n.f != math.Trunc(n.f) || n.f < -(1<<63) || n.f >= 1<<63 {
+		return 0, false
+	}
+	return int64(n.f), true
+}
+
+// Float64 returns the number as a float64. Integers outside the range a
+// float64 represents exactly are rounded.
+func (n Number) Float64() float64 {
+	if n.isInt {
+		return float64(n.i)
+	}
+	return n.f
+}
+
+// Lexeme returns the text the number was parsed from, or an empty string if
+// it was created in Go.
+func (n Number) Lexeme() string {
+	return n.lexeme
+}
+
+// String returns the original lexeme if there is one, otherwise a formatting
+// of the value that parses back to the same integer or float.
+func (n Number) String() string {
+	if n.lexeme != "" {
+		return n.lexeme
+	}
+	if n.isInt {
+		return strconv.FormatInt(n.i, 10)
+	}
+	s := strconv.FormatFloat(n.f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// Equals reports whether two numbers have the same mathematical value. As in
+// Lua, the integer 1 equals the float 1.0.
+func (n Number) Equals(o Number) bool {
+	if n.isInt && o.isInt {
+		return n.i == o.i
+	}
+	if n.isInt != o.isInt {
+		i, ok := n.Int64()
+		j, oOk := o.Int64()
+		return ok && oOk && i == j
+	}
+	return n.f == o.f
+}
+
+// Less reports whether n is less than o, comparing integers and floats
+// exactly as Lua does, so integers too large for a float64 to tell apart
+// still order correctly. NaN is not less than anything.
+func (n Number) Less(o Number) bool {
+	switch {
+	case n.isInt && o.isInt:
+		return n.i < o.i
+	case !n.isInt && !o.isInt:
+		return n.f < o.f
+	case n.isInt:
+		// i < f exactly when i < ceil(f)
+		if math.IsNaN(o.f) {
+			return false
+		}
+		c := math.Ceil(o.f)
+		switch {
+		case c >= 1<<63:
+			return true
+		case c < -(1 << 63):
+			return false
+		}
+		return n.i < int64(c)
+	}
+	// f < i exactly when floor(f) < i
+	if math.IsNaN(n.f) {
+		return false
+	}
+	f := math.Floor(n.f)
+	switch {
+	case f >= 1<<63:
+		return false
+	case f < -(1 << 63):
+		return true
+	}
+	return int64(f) < o.i
+}
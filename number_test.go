@@ -0,0 +1,90 @@
+package wowlua
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		lexeme   string
+		is_int   bool
+		as_int   int64
+		as_float float64
+	}{
+		{"0", true, 0, 0},
+		{"-1", true, -1, -1},
+		{"9007199254740993", true, 9007199254740993, 9007199254740992},
+		{"0xff", true, 255, 255},
+		{"0xffffffffffffffff", true, -1, -1},
+		{"1.5", false, 0, 1.5},
+		{"-.5", false, 0, -0.5},
+		{"1e3", false, 1000, 1000},
+		{"1.5e-3", false, 0, 0.0015},
+		{"9223372036854775808", false, 0, 9223372036854775808},
+	}
+	for _, c := range cases {
+		n, err := ParseNumber(c.lexeme)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", c.lexeme, err)
+			continue
+		}
+		if n.IsInteger() != c.is_int {
+			t.Errorf("Expected %q integer to be %v, got %v", c.lexeme, c.is_int, n.IsInteger())
+		}
+		if i, _ := n.Int64(); i != c.as_int {
+			t.Errorf("Expected %q as int64 to be %v, got %v", c.lexeme, c.as_int, i)
+		}
+		if f := n.Float64(); f != c.as_float {
+			t.Errorf("Expected %q as float64 to be %v, got %v", c.lexeme, c.as_float, f)
+		}
+		if s := n.String(); s != c.lexeme {
+			t.Errorf("Expected %q to round trip, got %q", c.lexeme, s)
+		}
+	}
+	for _, bad := range []string{"", "-", "1.2.3", "0x", "1e", "abc"} {
+		if _, err := ParseNumber(bad); err == nil {
+			t.Errorf("Expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestParsedNumberNodes(t *testing.T) {
+	tab, err := ParseLua(`Money = 123456789012345678 Ratio = 0.25`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	money := tab.GetByString("Money")
+	if !money.IsInteger() || money.GetInt64() != 123456789012345678 {
+		t.Errorf("Expected exact integer 123456789012345678, got %v", money)
+	}
+	ratio := tab.GetByString("Ratio")
+	if ratio.IsInteger() || ratio.GetFloat64() != 0.25 {
+		t.Errorf("Expected float 0.25, got %v", ratio)
+	}
+}
+
+func TestNumberLess(t *testing.T) {
+	cases := []struct {
+		a, b     Number
+		expected bool
+	}{
+		{IntNumber(9007199254740992), IntNumber(9007199254740993), true},
+		{IntNumber(9007199254740993), IntNumber(9007199254740992), false},
+		{IntNumber(9007199254740993), FloatNumber(9007199254740992), false},
+		{FloatNumber(9007199254740992), IntNumber(9007199254740993), true},
+		{IntNumber(1), FloatNumber(1.5), true},
+		{FloatNumber(1.5), IntNumber(1), false},
+		{IntNumber(-1), FloatNumber(-1.5), false},
+		{IntNumber(1 << 62), FloatNumber(math.Inf(1)), true},
+		{FloatNumber(math.Inf(-1)), IntNumber(-1 << 63), true},
+		{IntNumber(1), FloatNumber(math.NaN()), false},
+		{FloatNumber(math.NaN()), IntNumber(1), false},
+		{IntNumber(2), FloatNumber(2), false},
+	}
+	for _, c := range cases {
+		if c.a.Less(c.b) != c.expected {
+			t.Errorf("Expected %v < %v to be %v", c.a, c.b, c.expected)
+		}
+	}
+}
@@ -3,22 +3,35 @@ package wowlua
 import (
 	"errors"
 	"fmt"
+	"io"
 )
 
 // Parser handles parsing tokens into Nodes
 type Parser struct {
-	stack []*Node
+	stack      []*Node
+	open       []*Node // Table nodes whose closing brace hasn't been seen
+	positional []int   // Count of positional values in each open table
+	interner   *Interner
 }
 
-// NewParser creates a new parser.
+// NewParser creates a new parser. String keys are interned with a new
+// Interner; see UseInterner.
 func NewParser() *Parser {
 	p := &Parser{
-		stack: make([]*Node, 0),
+		stack:    make([]*Node, 0),
+		interner: NewInterner(),
 	}
 	p.startTable() // Top level table
 	return p
 }
 
+// UseInterner sets the Interner used for string keys. Sharing an Interner
+// between parsers shares keys between the trees they produce. A nil Interner
+// turns interning off.
+func (p *Parser) UseInterner(in *Interner) {
+	p.interner = in
+}
+
 // Push a node onto the current parse stack
 func (p *Parser) Push(n *Node) {
 	p.stack = append(p.stack, n)
@@ -49,10 +62,66 @@ func (p *Parser) startTable() {
 	t := NewTable()
 	n := &Node{nType: NodeTypeTable, value: t}
 	p.Push(n)
+	p.open = append(p.open, n)
+	p.positional = append(p.positional, 0)
+}
+
+// isOpen returns whether the node is the innermost table still being parsed.
+func (p *Parser) isOpen(n *Node) bool {
+	return len(p.open) > 0 && p.open[len(p.open)-1] == n
+}
+
+// completeValue pops the value on top of the stack and stores it in the table
+// it belongs to, either under the pending entry's key or as the next indexed
+// value.
+func (p *Parser) completeValue() error {
+	v := p.Pop()
+	top := p.Peek()
+	if v == nil || top == nil {
+		return p.bailout("Value found outside of any table")
+	}
+	switch top.nType {
+	case nodeTypeTableEntry:
+		e, ok := top.value.(*tableEntry)
+		if !ok {
+			return p.bailout("TableEntryValue not tableEntry!")
+		}
+		if e.key == nil {
+			return p.bailout("Found value for table entry without key.")
+		}
+		p.Pop()
+		table := p.Peek().GetTable()
+		if table == nil {
+			return p.bailout("Key not in table!")
+		}
+		if e.key.IsNil() {
+			return p.bailout("Found nil table key.")
+		}
+		table.Set(e.key, v)
+	case NodeTypeTable:
+		if !p.isOpen(top) {
+			return p.bailout("Found value after closed table.")
+		}
+		// Positional values are numbered as they appear, counting nils, so
+		// {1, nil, 3} has keys 1 and 3.
+		last := len(p.positional) - 1
+		p.positional[last]++
+		top.GetTable().Set(intKey(p.positional[last]), v)
+	default:
+		return p.bailout("Comma found outside table, table key")
+	}
+	return nil
+}
+
+// hasPendingValue returns whether the top of the stack is a value that hasn't
+// been stored in its table yet.
+func (p *Parser) hasPendingValue() bool {
+	top := p.Peek()
+	return top != nil && top.nType != nodeTypeTableEntry && !p.isOpen(top)
 }
 
 func (p *Parser) startKey() {
-	n := &Node{nType: NodeTypeTableEntry, value: &tableEntry{}}
+	n := &Node{nType: nodeTypeTableEntry, value: &tableEntry{}}
 	p.Push(n)
 }
 
@@ -65,6 +134,8 @@ func tokenToNode(t *Token) (*Node, error) {
 			n = NewNode(NodeTypeBool, true)
 		case "false":
 			n = NewNode(NodeTypeBool, false)
+		case "nil":
+			n = NewNode(NodeTypeNil, nil)
 		default:
 			n = NewNode(NodeTypeString, t.Value)
 		}
@@ -72,7 +143,15 @@ func tokenToNode(t *Token) (*Node, error) {
 		n = NewNode(NodeTypeString, t.Value)
 	case TokenTypeNumber:
 		logger.Debugf("TokenToNode: %q", t)
-		n = NewNode(NodeTypeNumber, t.Value)
+		if t.Number.Lexeme() != "" {
+			n = NewNode(NodeTypeNumber, t.Number)
+		} else {
+			num, err := ParseNumber(t.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", err, t.Value)
+			}
+			n = NewNode(NodeTypeNumber, num)
+		}
 	default:
 		return nil, fmt.Errorf("can't convert this token: %q", t)
 	}
@@ -88,21 +167,26 @@ func (p *Parser) Next(t *Token) error {
 	switch t.Type {
 	case TokenTypeIdentifier:
 		top := p.Peek()
-		switch top.nType {
-		case NodeTypeTableEntry:
-			n, err := tokenToNode(t)
-			if err != nil {
+		if top.nType == nodeTypeTableEntry || isKeyword(t.Value) {
+			return p.handleValueToken(t)
+		}
+		// Top-level assignments aren't separated by commas, so a name
+		// following a complete value starts the next entry.
+		if p.hasPendingValue() {
+			if err := p.completeValue(); err != nil {
 				return err
 			}
-			p.Push(n)
-		default:
-			p.Next(tokenStartKey)
-			p.Next(NewToken(TokenTypeString, t.Value))
-			p.Next(tokenEndKey)
 		}
+		if err := p.Next(tokenStartKey); err != nil {
+			return err
+		}
+		if err := p.Next(NewToken(TokenTypeString, t.Value)); err != nil {
+			return err
+		}
+		return p.Next(tokenEndKey)
 	case TokenTypeEquals:
 		top := p.Peek()
-		if top.nType != NodeTypeTableEntry {
+		if top.nType != nodeTypeTableEntry {
 			return p.bailout("Found equals with non table entry.")
 		}
 		if top.value == nil {
@@ -114,28 +198,26 @@ func (p *Parser) Next(t *Token) error {
 	case TokenTypeStartTable:
 		p.startTable()
 	case TokenTypeEndTable:
-		top := p.Peek()
-		if top.nType != NodeTypeTable {
-			v := p.Pop()
-			top = p.Peek()
-			if top.nType == NodeTypeTableEntry {
-				if e, ok := top.value.(*tableEntry); ok {
-					p.Pop()
-					top = p.Peek()
-					if top.nType != NodeTypeTable {
-						return p.bailout("Key not in table, when closing the table!")
-					}
-					if table, ok := top.value.(*Table); ok {
-						table.Set(e.key, v)
-					} else {
-						return p.bailout("Expected table node on top of stack.")
-					}
-				} else {
-					return p.bailout("TableEntryValue not tableEntry!")
-				}
+		if p.hasPendingValue() {
+			if err := p.completeValue(); err != nil {
+				return err
 			}
 		}
+		if !p.isOpen(p.Peek()) || len(p.open) == 1 {
+			return p.bailout("Found end of table without matching start.")
+		}
+		p.open = p.open[:len(p.open)-1]
+		p.positional = p.positional[:len(p.positional)-1]
+		// A finished top-level table has nothing after it to complete it.
+		if len(p.open) == 1 {
+			return p.completeValue()
+		}
 	case TokenTypeStartKey:
+		if p.hasPendingValue() && len(p.open) == 1 {
+			if err := p.completeValue(); err != nil {
+				return err
+			}
+		}
 		if p.Peek().nType != NodeTypeTable {
 			return p.bailout(fmt.Sprintf("Found start of key under %q", p.Peek()))
 		}
@@ -144,7 +226,7 @@ func (p *Parser) Next(t *Token) error {
 		key := p.Pop()
 		logger.Debugf("Popped Key: %v", key)
 		top := p.Peek()
-		if top.nType != NodeTypeTableEntry {
+		if top == nil || top.nType != nodeTypeTableEntry {
 			return p.bailout("Found end key without table entry.")
 		}
 		if top.value == nil {
@@ -154,34 +236,18 @@ func (p *Parser) Next(t *Token) error {
 			if e.key != nil {
 				return p.bailout("Found end key with key already set.")
 			}
+			if p.interner != nil && key.nType == NodeTypeString {
+				key = p.interner.key(key.GetString())
+			}
 			e.key = key
 		} else {
 			return p.bailout("Found end key on non-table-entry")
 		}
 	case TokenTypeComma:
-		v := p.Pop()
-		top := p.Peek()
-		switch top.nType {
-		case NodeTypeTableEntry:
-			if e, ok := top.value.(*tableEntry); ok {
-				p.Pop()
-				top = p.Peek()
-				if top.nType != NodeTypeTable {
-					return p.bailout("Key not in table!")
-				}
-				if table, ok := top.value.(*Table); ok {
-					table.Set(e.key, v)
-				} else {
-					return p.bailout("Expected table node on top of stack.")
-				}
-			} else {
-				return p.bailout("TableEntryValue not tableEntry!")
-			}
-		case NodeTypeTable:
-			top.GetTable().AddIndexed(v)
-		default:
-			return p.bailout("Comma found outside table, table key")
+		if !p.hasPendingValue() {
+			return p.bailout("Found comma without a value.")
 		}
+		return p.completeValue()
 	case TokenTypeString:
 		return p.handleValueToken(t)
 	case TokenTypeNumber:
@@ -192,9 +258,15 @@ func (p *Parser) Next(t *Token) error {
 	return nil
 }
 
+// isKeyword returns whether the identifier is a Lua keyword that is a value
+// rather than a name.
+func isKeyword(s string) bool {
+	return s == "true" || s == "false" || s == "nil"
+}
+
 func (p *Parser) handleValueToken(t *Token) error {
 	top := p.Peek()
-	if top.nType != NodeTypeTable && top.nType != NodeTypeTableEntry {
+	if !p.isOpen(top) && top.nType != nodeTypeTableEntry {
 		return p.bailout(fmt.Sprintf("Found value %q outside of table/key!", t))
 	}
 	n, err := tokenToNode(t)
@@ -225,43 +297,19 @@ func (p *Parser) bailout(msg string) error {
 
 // Finish parses all available tokens and returns the resulting table.
 func (p *Parser) Finish() (*Table, error) {
-	for n := p.Pop(); n != nil; n = p.Pop() {
-		top := p.Peek()
-		switch n.nType {
-		case NodeTypeTableEntry:
-			if e, ok := n.value.(*tableEntry); ok {
-				if top.nType != NodeTypeTable {
-					return nil, p.bailout("Key not in table!")
-				}
-				if table, ok := top.value.(*Table); ok {
-					table.Set(e.key, e.value)
-				} else {
-					return nil, p.bailout("Expected table node on top of stack.")
-				}
-			} else {
-				logger.Debugf("Stack Popped: ", n)
-				return nil, p.bailout("TableEntryValue not tableEntry!")
-			}
-		default:
-			if top != nil {
-				switch top.nType {
-				case NodeTypeTableEntry:
-					if e, ok := top.value.(*tableEntry); ok {
-						e.value = n
-					} else {
-						return nil, p.bailout("Top is marked tableEntry but isn't a *tableEntry")
-					}
-				default:
-					return nil, p.bailout("SHIT BROKE SON")
-				}
-			} else {
-				logger.Debugf("--> TOP is NIL <--")
-				if t, ok := n.value.(*Table); ok {
-					return t, nil
-				}
-			}
+	if len(p.open) != 1 {
+		return nil, p.bailout("Unterminated table at end of input.")
+	}
+	for len(p.stack) > 1 {
+		if !p.hasPendingValue() {
+			return nil, p.bailout("Table entry without value at end of input.")
+		}
+		if err := p.completeValue(); err != nil {
+			return nil, err
 		}
-
+	}
+	if t := p.Pop().GetTable(); t != nil {
+		return t, nil
 	}
 
 	err := errors.New("final result not a table")
@@ -271,10 +319,29 @@ func (p *Parser) Finish() (*Table, error) {
 
 // ParseLua handles end to end parsing of a string containing Lua table data
 func ParseLua(data string) (*Table, error) {
+	return ParseLuaInterned(data, NewInterner())
+}
+
+// ParseLuaInterned is like ParseLua but interns string keys with the provided
+// Interner, which may be shared with other parses. A nil Interner turns
+// interning off.
+func ParseLuaInterned(data string, in *Interner) (*Table, error) {
 	p := NewParser()
-	t := NewTokenizer(data, p.Next)
-	if err := t.Tokenize(); err != nil {
-		return nil, err
+	p.UseInterner(in)
+	t := NewTokenizer(data, nil)
+	var tok Token // Reused for every token; the parser doesn't keep it
+	var err error
+	for {
+		tok, err = t.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.Next(&tok); err != nil {
+			return nil, err
+		}
 	}
 	return p.Finish()
 }
@@ -0,0 +1,197 @@
+package wowlua
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPath indicates a path string couldn't be parsed
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+// A Path is the sequence of keys leading from the root of a tree to a node.
+// The root itself has an empty path. Keys are usually strings, integers or
+// bools.
+//
+// Paths have a string form, produced by String and read by ParsePath, where
+// string keys are separated by slashes and other keys are in brackets:
+//
+//	Characters/"Moon Guard"/Volne[1]/title
+//
+// String keys that aren't Lua names are quoted. When parsing, quotes are only
+// required for keys containing any of / [ ] " or that should be read with
+// escapes; a bracketed key may also be a quoted string.
+type Path []*Node
+
+// NewPath creates a path from keys. Keys may be anything accepted by Map.
+// NewPath panics on keys Map would panic on.
+func NewPath(keys ...interface{}) Path {
+	p := make(Path, len(keys))
+	for i, k := range keys {
+		p[i] = NodeOf(mustKey(k))
+	}
+	return p
+}
+
+// ParsePath parses the string form of a path. An empty string is the empty
+// path.
+func ParsePath(s string) (Path, error) {
+	var p Path
+	pos := 0
+	for pos < len(s) {
+		if s[pos] == '[' {
+			k, n, err := parsePathIndex(s[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("%w %q at offset %d: %v", ErrInvalidPath, s, pos, err)
+			}
+			p = append(p, k)
+			pos += n
+			continue
+		}
+		if len(p) > 0 {
+			if s[pos] != '/' {
+				return nil, fmt.Errorf("%w %q at offset %d: expected '/' or '['", ErrInvalidPath, s, pos)
+			}
+			pos++
+		}
+		k, n, err := parsePathName(s[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("%w %q at offset %d: %v", ErrInvalidPath, s, pos, err)
+		}
+		p = append(p, k)
+		pos += n
+	}
+	return p, nil
+}
+
+// parsePathName parses a bare or quoted string key from the start of s and
+// returns the key and how many bytes it used.
+func parsePathName(s string) (*Node, int, error) {
+	if strings.HasPrefix(s, `"`) {
+		str, n, err := parseQuoted(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		return NewNode(NodeTypeString, str), n, nil
+	}
+	n := strings.IndexAny(s, `/[]"`)
+	if n < 0 {
+		n = len(s)
+	}
+	if n == 0 {
+		return nil, 0, errors.New("empty key")
+	}
+	return NewNode(NodeTypeString, s[:n]), n, nil
+}
+
+// parsePathIndex parses a bracketed key from the start of s and returns the
+// key and how many bytes it used.
+func parsePathIndex(s string) (*Node, int, error) {
+	if strings.HasPrefix(s, `["`) {
+		str, n, err := parseQuoted(s[1:])
+		if err != nil {
+			return nil, 0, err
+		}
+		if !strings.HasPrefix(s[1+n:], "]") {
+			return nil, 0, errors.New("expected ']'")
+		}
+		return NewNode(NodeTypeString, str), n + 2, nil
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return nil, 0, errors.New("expected ']'")
+	}
+	switch inner := s[1:end]; inner {
+	case "true":
+		return NewNode(NodeTypeBool, true), end + 1, nil
+	case "false":
+		return NewNode(NodeTypeBool, false), end + 1, nil
+	default:
+		num, err := ParseNumber(inner)
+		if err != nil {
+			return nil, 0, fmt.Errorf("bad index %q", inner)
+		}
+		return NewNode(NodeTypeNumber, num), end + 1, nil
+	}
+}
+
+// parseQuoted parses a Go-style quoted string from the start of s and returns
+// the unquoted string and how many bytes it used.
+func parseQuoted(s string) (string, int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			str, err := strconv.Unquote(s[:i+1])
+			return str, i + 1, err
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+// String returns the path in the form read by ParsePath.
+func (p Path) String() string {
+	var b strings.Builder
+	for i, k := range p {
+		switch k.GetType() {
+		case NodeTypeString, NodeTypeIdentifier:
+			if i > 0 {
+				b.WriteByte('/')
+			}
+			if s := k.GetString(); IsName(s) {
+				b.WriteString(s)
+			} else {
+				b.WriteString(strconv.Quote(s))
+			}
+		case NodeTypeNumber:
+			b.WriteString("[" + k.GetNumber().String() + "]")
+		case NodeTypeBool:
+			b.WriteString("[" + strconv.FormatBool(k.GetBool()) + "]")
+		default:
+			b.WriteString("[" + k.GetType().String() + "]")
+		}
+	}
+	return b.String()
+}
+
+// clone returns a copy of the path that doesn't share its backing array.
+func (p Path) clone() Path {
+	return append(Path(nil), p...)
+}
+
+// child returns a new path with the key appended. The result never shares
+// its backing array with p, so callbacks may keep the paths they're given.
+func (p Path) child(k *Node) Path {
+	return append(p[:len(p):len(p)], k)
+}
+
+var luaKeywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true,
+	"end": true, "false": true, "for": true, "function": true, "goto": true,
+	"if": true, "in": true, "local": true, "nil": true, "not": true,
+	"or": true, "repeat": true, "return": true, "then": true, "true": true,
+	"until": true, "while": true,
+}
+
+// IsName returns whether s is a valid Lua name: letters, digits and
+// underscores, not starting with a digit and not a keyword. Keys that are
+// names can be written bare, as in {name = 1}.
+func IsName(s string) bool {
+	if s == "" || luaKeywords[s] {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package wowlua
+
+import (
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	cases := []struct {
+		in        string
+		canonical string
+		keys      int
+	}{
+		{``, ``, 0},
+		{`Characters/"Moon Guard"/Volne[1]`, `Characters/"Moon Guard"/Volne[1]`, 4},
+		{`Characters/Moon Guard/Volne[1]/title`, `Characters/"Moon Guard"/Volne[1]/title`, 5},
+		{`[1][2]/"a/b"`, `[1][2]/"a/b"`, 3},
+		{`flags[true]["x y"]`, `flags[true]/"x y"`, 3},
+		{`"tab\there"[-1]`, `"tab\there"[-1]`, 2},
+	}
+	for _, c := range cases {
+		p, err := ParsePath(c.in)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", c.in, err)
+			continue
+		}
+		if len(p) != c.keys {
+			t.Errorf("Expected %q to have %v keys, got %v", c.in, c.keys, len(p))
+		}
+		if p.String() != c.canonical {
+			t.Errorf("Expected %q to format as %q, got %q", c.in, c.canonical, p)
+		}
+		again, err := ParsePath(p.String())
+		if err != nil || len(again) != len(p) {
+			t.Errorf("Expected %q to round trip, got %v, %v", p, again, err)
+			continue
+		}
+		for i := range p {
+			if !p[i].Equals(again[i]) {
+				t.Errorf("Expected key %v of %q to round trip, got %v", i, p, again[i])
+			}
+		}
+	}
+	for _, bad := range []string{`/a`, `a//b`, `a[1`, `a[x]`, `"a`, `a]`} {
+		if _, err := ParsePath(bad); err == nil {
+			t.Errorf("Expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestGetParsedPath(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	p, err := ParsePath(`HarbingerTools_Events/Characters/"Moon Guard"/Volne[3]/day`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing path: %v", err)
+	}
+	_, n, err := tab.GetPath(p...)
+	if err != nil || n.GetInt64() != 20 {
+		t.Errorf("Expected day 20, got %v, %v", n, err)
+	}
+}
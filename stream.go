@@ -0,0 +1,220 @@
+package wowlua
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// A Decoder reads the top-level assignments of SavedVariables text and
+// decodes their values one at a time, so a file holding several variables
+// can be decoded into a separate Go value for each. The input is read as
+// it's needed, and only the assignment being decoded is parsed into a table.
+type Decoder struct {
+	r        *bufio.Reader
+	tz       *Tokenizer // Over the input read but not yet tokenized
+	eof      bool       // Whether r has been read to the end
+	offset   int        // Offset in the input of the start of tz's text
+	line     int        // Lines in the input before tz's text
+	column   int        // Bytes on the line before tz's text
+	peeked   *Token
+	interner *Interner
+	name     string
+	opts     decoder
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), tz: NewTokenizer("", nil), interner: NewInterner()}
+}
+
+// DisallowUnknownFields makes Decode return an error wrapping
+// ErrUnknownField when a table decoded into a struct has an entry with no
+// matching field.
+func (d *Decoder) DisallowUnknownFields() {
+	d.opts.disallowUnknownFields = true
+}
+
+// UseNumber makes Decode store numbers decoded into an interface{} as Number
+// instead of int64 or float64.
+func (d *Decoder) UseNumber() {
+	d.opts.useNumber = true
+}
+
+// More returns whether there's another assignment to decode.
+func (d *Decoder) More() bool {
+	tok, err := d.assignmentStart()
+	if err != nil {
+		return false
+	}
+	d.peeked = &tok
+	return true
+}
+
+// Name returns the variable name of the assignment last decoded.
+func (d *Decoder) Name() string {
+	return d.name
+}
+
+// Decode decodes the value of the next top-level assignment into the value
+// pointed to by v, as Table.Decode does, and returns io.EOF when there are
+// none left. Use Name to get the variable assigned.
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrNotPointer
+	}
+	key, n, err := d.next()
+	if err != nil {
+		return err
+	}
+	dec := d.opts
+	dec.value(Path{key}, n, rv.Elem())
+	return dec.err
+}
+
+// next parses the next assignment and returns its name and value.
+func (d *Decoder) next() (*Node, *Node, error) {
+	tok, err := d.assignmentStart()
+	if err != nil {
+		return nil, nil, err
+	}
+	if tok.Type != TokenTypeIdentifier || isKeyword(tok.Value) {
+		return nil, nil, d.errorf(tok.Offset, "expected a variable name")
+	}
+	d.name = tok.Value
+
+	p := NewParser()
+	p.UseInterner(d.interner)
+	if err := p.Next(&tok); err != nil {
+		return nil, nil, err
+	}
+	// Feed the parser the '=' and then tokens until the value is complete
+	depth := 0
+	for i := 0; i < 2 || depth > 0; i++ {
+		tok, err := d.token()
+		if err == io.EOF {
+			return nil, nil, d.errorf(d.offset+len(d.tz.src), "unexpected end of input")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 && tok.Type != TokenTypeEquals {
+			return nil, nil, d.errorf(tok.Offset, "expected '='")
+		}
+		switch tok.Type {
+		case TokenTypeStartTable:
+			depth++
+		case TokenTypeEndTable:
+			depth--
+		}
+		if err := p.Next(&tok); err != nil {
+			return nil, nil, err
+		}
+	}
+	t, err := p.Finish()
+	if err != nil {
+		return nil, nil, err
+	}
+	key := d.interner.key(d.name)
+	n := t.Get(key)
+	if n == nil {
+		n = NodeOf(Nil{})
+	}
+	return key, n, nil
+}
+
+// assignmentStart returns the first token of the next assignment, skipping
+// separators after the previous one.
+func (d *Decoder) assignmentStart() (Token, error) {
+	for {
+		tok, err := d.token()
+		if err != nil || tok.Type != TokenTypeComma {
+			return tok, err
+		}
+	}
+}
+
+// token returns the next token that isn't a comment, with offsets in the
+// whole input.
+func (d *Decoder) token() (Token, error) {
+	if d.peeked != nil {
+		tok := *d.peeked
+		d.peeked = nil
+		return tok, nil
+	}
+	for {
+		start := d.tz.pos
+		tok, err := d.tz.Next()
+		// A token is only known to be complete once there's text after it
+		if !d.eof && (err != nil || tok.End == len(d.tz.src)) {
+			d.tz.pos = start
+			if err := d.fill(); err != nil {
+				return Token{}, err
+			}
+			continue
+		}
+		if err != nil {
+			return tok, d.syntaxError(err)
+		}
+		if tok.Type != TokenTypeIgnore {
+			tok.Offset += d.offset
+			tok.End += d.offset
+			return tok, nil
+		}
+	}
+}
+
+// fill drops the text that's been tokenized and reads more. At least as
+// much is read as is kept, so a long token takes few reads.
+func (d *Decoder) fill() error {
+	src := d.tz.src
+	done := src[:d.tz.pos]
+	if i := strings.LastIndexByte(done, '\n'); i >= 0 {
+		d.line += strings.Count(done, "\n")
+		d.column = len(done) - i - 1
+	} else {
+		d.column += len(done)
+	}
+	d.offset += len(done)
+	rest := src[len(done):]
+
+	size := 4096
+	if len(rest) > size {
+		size = len(rest)
+	}
+	buf := make([]byte, len(rest), len(rest)+size)
+	copy(buf, rest)
+	for len(buf) == len(rest) && !d.eof {
+		n, err := d.r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		switch {
+		case err == io.EOF:
+			d.eof = true
+		case err != nil:
+			return err
+		}
+	}
+	d.tz = NewTokenizerBytes(buf, nil)
+	return nil
+}
+
+// errorf returns a SyntaxError at offset in the whole input, which must be
+// in the text that hasn't been dropped.
+func (d *Decoder) errorf(offset int, tmpl string, v ...interface{}) error {
+	return d.syntaxError(d.tz.errorf(offset-d.offset, tmpl, v...))
+}
+
+// syntaxError moves the position of a SyntaxError from the tokenizer's text
+// to the whole input.
+func (d *Decoder) syntaxError(err error) error {
+	if e, ok := err.(*SyntaxError); ok {
+		e.Offset += d.offset
+		if e.Line == 1 {
+			e.Column += d.column
+		}
+		e.Line += d.line
+	}
+	return err
+}
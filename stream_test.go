@@ -0,0 +1,102 @@
+package wowlua
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	input := `-- Two variables and a scalar
+Config = { ["scale"] = 1.5, ["channels"] = { "GUILD", "RAID" } }
+Version = 3;
+Log = { [1] = "first", [2] = 2 }
+`
+	dec := NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+
+	var config struct {
+		Scale    float64  `lua:"scale"`
+		Channels []string `lua:"channels"`
+	}
+	if err := dec.Decode(&config); err != nil {
+		t.Fatalf("Unexpected error decoding Config: %v", err)
+	}
+	if dec.Name() != "Config" || config.Scale != 1.5 || len(config.Channels) != 2 {
+		t.Errorf("Expected Config with scale 1.5 and two channels, got %s = %+v", dec.Name(), config)
+	}
+
+	var version int
+	if err := dec.Decode(&version); err != nil || dec.Name() != "Version" || version != 3 {
+		t.Errorf("Expected Version = 3, got %s = %d, error %v", dec.Name(), version, err)
+	}
+
+	if !dec.More() {
+		t.Fatalf("Expected More before Log")
+	}
+	var log []interface{}
+	if err := dec.Decode(&log); err != nil {
+		t.Fatalf("Unexpected error decoding Log: %v", err)
+	}
+	if n, ok := log[1].(Number); !ok || !n.Equals(IntNumber(2)) {
+		t.Errorf("Expected Number 2 with UseNumber, got %#v", log[1])
+	}
+
+	if dec.More() {
+		t.Errorf("Expected no More after Log")
+	}
+	if err := dec.Decode(&log); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last assignment, got %v", err)
+	}
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`Config = { ["scale"] = 1, ["extra"] = true }`))
+	dec.DisallowUnknownFields()
+	var config struct {
+		Scale int `lua:"scale"`
+	}
+	err := dec.Decode(&config)
+	var path_err *PathError
+	if !errors.As(err, &path_err) || !errors.Is(err, ErrUnknownField) || path_err.Path.String() != "Config/extra" {
+		t.Errorf("Expected ErrUnknownField at Config/extra, got %v", err)
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("Config = { 1, 2\n"))
+	var v interface{}
+	err := dec.Decode(&v)
+	var syntax_err *SyntaxError
+	if !errors.As(err, &syntax_err) {
+		t.Errorf("Expected a SyntaxError for an unterminated table, got %v", err)
+	}
+}
+
+func TestDecoderIncremental(t *testing.T) {
+	input := "-- Read a byte at a time\nA = { [\"long key\"] = 12345, [[x]] }\n\nB = { 1.5,\n  [2] = 'two' }\nC = { 1, } }"
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	dec.UseNumber()
+	var a map[interface{}]interface{}
+	if err := dec.Decode(&a); err != nil {
+		t.Fatalf("Unexpected error decoding A: %v", err)
+	}
+	if n, ok := a["long key"].(Number); !ok || !n.Equals(IntNumber(12345)) || a[IntNumber(1)] != "x" {
+		t.Errorf("Expected A with number keys kept as Number, got %#v", a)
+	}
+	var b []interface{}
+	if err := dec.Decode(&b); err != nil || len(b) != 2 || b[1] != "two" {
+		t.Errorf("Expected B = {1.5, \"two\"}, got %v, error %v", b, err)
+	}
+	var c []int
+	if err := dec.Decode(&c); err != nil || len(c) != 1 {
+		t.Errorf("Expected C = {1}, got %v, error %v", c, err)
+	}
+	err := dec.Decode(&c)
+	var syntax_err *SyntaxError
+	if !errors.As(err, &syntax_err) || syntax_err.Line != 6 || syntax_err.Column != 12 || syntax_err.Offset != len(input)-1 {
+		t.Errorf("Expected a SyntaxError at line 6, column 12, got %v", err)
+	}
+}
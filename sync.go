@@ -0,0 +1,56 @@
+package wowlua
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SyncTable holds a table that many goroutines can read while it's replaced
+// or updated. Readers Load the current table and may use it for as long as
+// they like; a table is never modified once it has been stored, so Load
+// needs no locking and readers never see a partial update. Writers replace
+// the table with Store or modify a copy with Update.
+//
+// Tables stored in a SyncTable must not be modified afterward, by the caller
+// or by readers.
+type SyncTable struct {
+	current atomic.Value // Holds a *Table
+	mu      sync.Mutex   // Serializes Update
+}
+
+// NewSyncTable creates a SyncTable holding t. A nil t is replaced by an empty
+// table.
+func NewSyncTable(t *Table) *SyncTable {
+	s := &SyncTable{}
+	s.Store(t)
+	return s
+}
+
+// Load returns the current table. The caller must treat it as read-only.
+func (s *SyncTable) Load() *Table {
+	return s.current.Load().(*Table)
+}
+
+// Store atomically replaces the current table with t. A nil t is replaced by
+// an empty table.
+func (s *SyncTable) Store(t *Table) {
+	if t == nil {
+		t = NewTable()
+	}
+	s.current.Store(t)
+}
+
+// Update calls fn with a deep copy of the current table and, if fn returns
+// nil, stores the copy. Concurrent Updates are applied one at a time so none
+// are lost; an Update racing with Store is applied to whichever table it
+// copied.
+func (s *SyncTable) Update(fn func(*Table) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.Load().DeepCopy()
+	if err := fn(t); err != nil {
+		return err
+	}
+	s.Store(t)
+	return nil
+}
@@ -0,0 +1,55 @@
+package wowlua
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSyncTableConcurrentAccess(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	s := NewSyncTable(tab)
+	day := NewPath("HarbingerTools_Events", "Characters", "Moon Guard", "Volne", 1, "day")
+
+	var wg sync.WaitGroup
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				current := s.Load()
+				if _, err := current.AsInt(day...); err != nil {
+					t.Errorf("Unexpected error reading: %v", err)
+					return
+				}
+				current.Walk(func(path Path, key, value *Node) WalkAction { return WalkContinue })
+			}
+		}()
+	}
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				err := s.Update(func(next *Table) error {
+					d, _ := next.AsInt(day...)
+					return next.SetPath(NodeOf(Int(d+1)), day...)
+				})
+				if err != nil {
+					t.Errorf("Unexpected error updating: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if d, _ := s.Load().AsInt(day...); d != 118 {
+		t.Errorf("Expected all 100 updates to apply, got day %v", d)
+	}
+	if d, _ := tab.AsInt(day...); d != 18 {
+		t.Errorf("Expected the original table to be unchanged, got day %v", d)
+	}
+}
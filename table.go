@@ -12,13 +12,24 @@ var (
 	ErrNotTable = errors.New("Node not a table")
 	// ErrWrongType indicates that the node was the wrong type
 	ErrWrongType = errors.New("Node is wrong type")
+	// ErrOutOfRange indicates a sequence position outside the sequence
+	ErrOutOfRange = errors.New("Position out of range")
+	// ErrEmptyPath indicates a path with no keys where at least one is needed
+	ErrEmptyPath = errors.New("Path is empty")
 )
 
 // Table is the top-level data structure returned by parsing. The table
 // consists of table entries. Each entry has a key and a value, each of type
 // Node.
+//
+// Methods that only read a table, such as Get, GetPath, Keys, Len, Equals
+// and Walk, are safe to call from many goroutines at once provided nothing
+// modifies the table or the tables nested in it meanwhile. Use SyncTable to
+// share a table that is replaced or updated while it's being read.
 type Table struct {
 	entries []*tableEntry
+	index   map[tableKey]int // Position of each key in entries
+	seqLen  int              // Keys 1 to seqLen are present and seqLen+1 isn't
 }
 
 // NewTable creates a new, empty table
@@ -43,7 +54,7 @@ func (t *Table) String() string {
 // HasKeyByString checks whether the table has an entry with the provided key
 // string. This does not parse numeric strings to compare to numeric keys.
 func (t *Table) HasKeyByString(s string) bool {
-	return t.HasKey(&Node{nType: NodeTypeString, value: s})
+	return t.HasKey(NewNode(NodeTypeString, s))
 }
 
 // HasKey checks whether the table has an entry with a key equal to the
@@ -53,28 +64,71 @@ func (t *Table) HasKey(k *Node) bool {
 }
 
 // Set an entry in table with the provided key-value pair. If an entry exists
-// with that key it is overwritten. If not it is added.
+// with that key it is overwritten. If not it is added. As in Lua, setting a
+// key to nil deletes it.
 func (t *Table) Set(k, v *Node) {
+	if v == nil || v.IsNil() {
+		t.Delete(k)
+		return
+	}
 	e := t.getEntry(k)
 	if e == nil {
 		e = &tableEntry{key: k}
+		if t.index == nil {
+			t.index = make(map[tableKey]int)
+		}
+		key := keyOf(k)
+		t.index[key] = len(t.entries)
 		t.entries = append(t.entries, e)
+		if key == seqKey(t.seqLen+1) {
+			t.seqLen++
+			for t.hasSeqKey(t.seqLen + 1) {
+				t.seqLen++
+			}
+		}
 	}
 	e.value = v
 }
 
 func (t *Table) getEntry(k *Node) *tableEntry {
-	for _, e := range t.entries {
-		if e.key.Equals(k) {
-			return e
-		}
+	i, ok := t.index[keyOf(k)]
+	if !ok {
+		return nil
 	}
-	return nil
+	return t.entries[i]
+}
+
+// Delete removes the entry with a key equal to the provided key. It returns
+// whether there was such an entry. Unlike Remove, no other keys are changed.
+func (t *Table) Delete(k *Node) bool {
+	key := keyOf(k)
+	i, ok := t.index[key]
+	if !ok {
+		return false
+	}
+	delete(t.index, key)
+	if pos, ok := key.value.(int64); ok && key.nType == NodeTypeNumber && pos >= 1 && pos <= int64(t.seqLen) {
+		t.seqLen = int(pos) - 1
+	}
+	copy(t.entries[i:], t.entries[i+1:])
+	t.entries[len(t.entries)-1] = nil
+	t.entries = t.entries[:len(t.entries)-1]
+	for ; i < len(t.entries); i++ {
+		t.index[keyOf(t.entries[i].key)] = i
+	}
+	return true
+}
+
+// DeleteByString removes the entry with a string key equal to the provided
+// string. It returns whether there was such an entry.
+func (t *Table) DeleteByString(s string) bool {
+	return t.Delete(NewNode(NodeTypeString, s))
 }
 
 // GetStringByString looks for an entry in the table with a string key equal to
-// the provided string and a value of type string. It returns an error if no
-// entry has a matching key or the matching entry node is not a string.
+// the provided string and a value of type string. It returns ErrNotFound if no
+// entry has a matching key or ErrWrongType if the matching entry node is not a
+// string. AsString gives errors that name the key.
 func (t *Table) GetStringByString(s string) (string, error) {
 	n := t.GetByString(s)
 	if n == nil {
@@ -87,8 +141,9 @@ func (t *Table) GetStringByString(s string) (string, error) {
 }
 
 // GetFloatByString looks for an entry in the table with a string key equal to
-// the provided string and a value of type Number. It returns an error if no
-// entry has a matching key or the matching entry node is not a Number.
+// the provided string and a value of type Number. It returns ErrNotFound if no
+// entry has a matching key or ErrWrongType if the matching entry node is not a
+// Number. AsFloat gives errors that name the key.
 func (t *Table) GetFloat64ByString(s string) (float64, error) {
 	n := t.GetByString(s)
 	if n == nil {
@@ -118,28 +173,114 @@ func (t *Table) Get(k *Node) *Node {
 // GetStringByPath walks through nested tables to find a node matching the
 // path. All keys in the path must be strings.
 func (t *Table) GetStringPath(path ...string) (*Table, *Node, error) {
-	pathNodes := make([]*Node, len(path))
+	return t.GetPath(stringPath(path)...)
+}
+
+func stringPath(path []string) Path {
+	p := make(Path, len(path))
 	for i, elem := range path {
-		pathNodes[i] = NewNode(NodeTypeString, elem)
+		p[i] = NewNode(NodeTypeString, elem)
 	}
-	return t.GetPath(pathNodes...)
+	return p
 }
 
-// GetPath walks through nested tables to find a node matching the
-// path.
+// GetPath walks through nested tables to find a node matching the path. It
+// returns the table containing the node along with the node. If the path
+// can't be followed, the error is ErrNotFound or ErrNotTable; the As
+// accessors, such as AsTable, give errors that name the level that failed.
 func (t *Table) GetPath(path ...*Node) (*Table, *Node, error) {
-	if !t.HasKey(path[0]) {
-		return t, nil, ErrNotFound
+	t, n, err := t.getPath(path)
+	if pathErr, ok := err.(*PathError); ok {
+		err = pathErr.Err
 	}
-	sub := t.Get(path[0])
-	if len(path) == 1 {
-		return t, sub, nil
+	return t, n, err
+}
+
+// getPath is GetPath with errors that are *PathErrors naming the level that
+// failed.
+func (t *Table) getPath(path []*Node) (*Table, *Node, error) {
+	if len(path) == 0 {
+		return t, nil, ErrEmptyPath
 	}
-	subT := sub.GetTable()
-	if subT == nil {
-		return t, sub, ErrNotTable
+	for i, k := range path {
+		sub := t.Get(k)
+		if sub == nil {
+			return t, nil, &PathError{Path: Path(path[:i+1]).clone(), Err: ErrNotFound}
+		}
+		if i == len(path)-1 {
+			return t, sub, nil
+		}
+		subT := sub.GetTable()
+		if subT == nil {
+			return t, sub, notTableError(path[:i+1], sub)
+		}
+		t = subT
+	}
+	return t, nil, nil
+}
+
+// SetStringPath stores the node at the path, creating missing intermediate
+// tables. All keys in the path must be strings.
+func (t *Table) SetStringPath(v *Node, path ...string) error {
+	return t.SetPath(v, stringPath(path)...)
+}
+
+// SetPath stores the node at the path, creating missing intermediate tables
+// like "mkdir -p". If an intermediate node exists but isn't a table, a
+// *PathError naming that level is returned and nothing is changed.
+func (t *Table) SetPath(v *Node, path ...*Node) error {
+	if len(path) == 0 {
+		return ErrEmptyPath
 	}
-	return subT.GetPath(path[1:]...)
+	parent, err := t.EnsureTable(path[:len(path)-1]...)
+	if err != nil {
+		return err
+	}
+	parent.Set(path[len(path)-1], v)
+	return nil
+}
+
+// EnsureTable returns the table at the path, creating it and any missing
+// intermediate tables. If a node on the path exists but isn't a table, a
+// *PathError naming that level is returned. An empty path returns t.
+func (t *Table) EnsureTable(path ...*Node) (*Table, error) {
+	for i, k := range path {
+		n := t.Get(k)
+		if n == nil {
+			for _, k := range path[i:] {
+				sub := NewTable()
+				t.Set(k, NodeOf(sub))
+				t = sub
+			}
+			return t, nil
+		}
+		if t = n.GetTable(); t == nil {
+			return nil, notTableError(path[:i+1], n)
+		}
+	}
+	return t, nil
+}
+
+// DeletePath removes the entry at the end of the path. If the entry or any
+// table leading to it is missing, a *PathError wrapping ErrNotFound is
+// returned.
+func (t *Table) DeletePath(path ...*Node) error {
+	if len(path) == 0 {
+		return ErrEmptyPath
+	}
+	for i, k := range path[:len(path)-1] {
+		n := t.Get(k)
+		if n == nil {
+			return &PathError{Path: Path(path[:i+1]).clone(), Err: ErrNotFound}
+		}
+		if t = n.GetTable(); t == nil {
+			return notTableError(path[:i+1], n)
+		}
+	}
+	if !t.Delete(path[len(path)-1]) {
+		return &PathError{Path: Path(path).clone(), Err: ErrNotFound}
+	}
+	return nil
 }
 
 // Keys returns all the keys in the table as a slice.
@@ -151,15 +292,108 @@ func (t *Table) Keys() []*Node {
 	return keys
 }
 
-// AddIndexed appends the node to the table. The key for the new node is the
-// current number of entries. This value is returned.
+// Range calls fn for each entry, in the order they were added, until fn
+// returns false. fn must not modify the table.
+func (t *Table) Range(fn func(k, v *Node) bool) {
+	for _, e := range t.entries {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// SeqLen returns the length of the table's sequence part, the number of
+// consecutive integer keys starting at 1. This is Lua's # operator for tables
+// without holes.
+func (t *Table) SeqLen() int {
+	return t.seqLen
+}
+
+// hasSeqKey returns whether the table has the integer key i.
+func (t *Table) hasSeqKey(i int) bool {
+	_, ok := t.index[seqKey(i)]
+	return ok
+}
+
+// GetIndexed returns the value at the given position in the sequence, or nil
+// if there isn't one.
+func (t *Table) GetIndexed(i int) *Node {
+	return t.Get(intKey(i))
+}
+
+// AddIndexed appends the node to the table's sequence. The key for the new
+// node is one more than the current sequence length. This value is returned.
 func (t *Table) AddIndexed(n *Node) int {
-	i := t.Len()
-	k := &Node{nType: NodeTypeNumber, value: i}
-	t.Set(k, n)
+	i := t.SeqLen() + 1
+	t.Set(intKey(i), n)
 	return i
 }
 
+// Insert adds the node to the sequence at position pos, shifting the elements
+// at pos and above up by one, like Lua's table.insert. The position may be
+// anywhere from 1 to one past the end of the sequence.
+func (t *Table) Insert(pos int, n *Node) error {
+	seqLen := t.SeqLen()
+	if pos < 1 || pos > seqLen+1 {
+		return ErrOutOfRange
+	}
+	for i := seqLen; i >= pos; i-- {
+		t.Set(intKey(i+1), t.GetIndexed(i))
+	}
+	t.Set(intKey(pos), n)
+	return nil
+}
+
+// Remove removes and returns the node at position pos of the sequence,
+// shifting the elements above it down by one, like Lua's table.remove.
+func (t *Table) Remove(pos int) (*Node, error) {
+	seqLen := t.SeqLen()
+	if pos < 1 || pos > seqLen {
+		return nil, ErrOutOfRange
+	}
+	n := t.GetIndexed(pos)
+	for i := pos; i < seqLen; i++ {
+		t.Set(intKey(i), t.GetIndexed(i+1))
+	}
+	t.Delete(intKey(seqLen))
+	return n, nil
+}
+
+// Move moves the sequence element at position from to position to, shifting
+// the elements between them to fill the gap.
+func (t *Table) Move(from, to int) error {
+	seqLen := t.SeqLen()
+	if from < 1 || from > seqLen || to < 1 || to > seqLen {
+		return ErrOutOfRange
+	}
+	n, err := t.Remove(from)
+	if err != nil {
+		return err
+	}
+	return t.Insert(to, n)
+}
+
+// Clone returns a shallow copy of the table. The new table has its own
+// entries but shares key and value nodes, including nested tables, with the
+// original.
+func (t *Table) Clone() *Table {
+	c := NewTable()
+	for _, e := range t.entries {
+		c.Set(e.key, e.value)
+	}
+	return c
+}
+
+// DeepCopy returns a copy of the table that shares nothing with the original.
+// Nested tables are copied recursively.
+func (t *Table) DeepCopy() *Table {
+	c := NewTable()
+	for _, e := range t.entries {
+		c.Set(e.key.DeepCopy(), e.value.DeepCopy())
+	}
+	return c
+}
+
 // Equals returns whether this table is equivalent to another table.
 func (t *Table) Equals(o *Table) bool {
 	if t == o {
@@ -168,7 +402,6 @@ func (t *Table) Equals(o *Table) bool {
 	if len(t.entries) != len(o.entries) {
 		return false
 	}
-	// TODO(jason): Make this faster than O(n^2), tho for small cases it's fine
 	for _, te := range t.entries {
 		oe := o.getEntry(te.key)
 		if oe == nil {
@@ -202,3 +435,36 @@ func (e *tableEntry) String() string {
 	}
 	return "{" + keyStr + ", " + valueStr + "}"
 }
+
+// tableKey is the comparable form of a key node used to index table entries.
+// Numbers with an integer value share a key whether they're integers or
+// floats, as they do in Lua. Tables used as keys compare by identity.
+type tableKey struct {
+	nType NodeType
+	value interface{}
+}
+
+func keyOf(n *Node) tableKey {
+	switch n.nType {
+	case NodeTypeNumber:
+		num := n.GetNumber()
+		if i, ok := num.Int64(); ok {
+			return tableKey{NodeTypeNumber, i}
+		}
+		return tableKey{NodeTypeNumber, num.Float64()}
+	case NodeTypeString, NodeTypeIdentifier:
+		return tableKey{n.nType, n.GetString()}
+	case NodeTypeBool:
+		return tableKey{NodeTypeBool, n.GetBool()}
+	}
+	return tableKey{n.nType, n.value}
+}
+
+// seqKey returns the tableKey of the integer key i.
+func seqKey(i int) tableKey {
+	return tableKey{NodeTypeNumber, int64(i)}
+}
+
+func intKey(i int) *Node {
+	return NewNode(NodeTypeNumber, IntNumber(int64(i)))
+}
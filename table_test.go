@@ -0,0 +1,135 @@
+package wowlua
+
+import (
+	"errors"
+	"testing"
+)
+
+func seqStrings(tab *Table) []string {
+	strs := make([]string, tab.SeqLen())
+	for i := range strs {
+		strs[i] = tab.GetIndexed(i + 1).GetString()
+	}
+	return strs
+}
+
+func expectSeq(t *testing.T, tab *Table, expected ...string) {
+	t.Helper()
+	got := seqStrings(tab)
+	if len(got) != len(expected) {
+		t.Fatalf("Expected sequence %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected sequence %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestSequenceMutation(t *testing.T) {
+	tab, err := ParseLua(`Seq = { "a", "b", "c", ["name"] = "x" }`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	seq := tab.GetByString("Seq").GetTable()
+	expectSeq(t, seq, "a", "b", "c")
+
+	if err := seq.Insert(2, NewNode(NodeTypeString, "z")); err != nil {
+		t.Fatalf("Unexpected error inserting: %v", err)
+	}
+	expectSeq(t, seq, "a", "z", "b", "c")
+
+	removed, err := seq.Remove(1)
+	if err != nil {
+		t.Fatalf("Unexpected error removing: %v", err)
+	}
+	if removed.GetString() != "a" {
+		t.Errorf("Expected to remove %q, got %v", "a", removed)
+	}
+	expectSeq(t, seq, "z", "b", "c")
+
+	if err := seq.Move(1, 3); err != nil {
+		t.Fatalf("Unexpected error moving: %v", err)
+	}
+	expectSeq(t, seq, "b", "c", "z")
+
+	if err := seq.Insert(5, NewNode(NodeTypeString, "q")); err != ErrOutOfRange {
+		t.Errorf("Expected ErrOutOfRange inserting past the end, got %v", err)
+	}
+	if !seq.DeleteByString("name") || seq.HasKeyByString("name") {
+		t.Errorf("Expected key %q to be deleted", "name")
+	}
+	if seq.Len() != 3 {
+		t.Errorf("Expected 3 entries after delete, got %v", seq.Len())
+	}
+}
+
+func TestSeqLenHoles(t *testing.T) {
+	tab := NewTable()
+	tab.Set(intKey(1), NewNode(NodeTypeString, "a"))
+	tab.Set(intKey(3), NewNode(NodeTypeString, "c"))
+	tab.Set(intKey(4), NewNode(NodeTypeString, "d"))
+	if tab.SeqLen() != 1 {
+		t.Errorf("Expected sequence length 1 with a hole at 2, got %d", tab.SeqLen())
+	}
+	tab.Set(NewNode(NodeTypeNumber, FloatNumber(2)), NewNode(NodeTypeString, "b"))
+	if tab.SeqLen() != 4 {
+		t.Errorf("Expected filling the hole to give length 4, got %d", tab.SeqLen())
+	}
+	tab.Delete(intKey(3))
+	if tab.SeqLen() != 2 {
+		t.Errorf("Expected deleting 3 to give length 2, got %d", tab.SeqLen())
+	}
+	tab.Set(intKey(2), NodeOf(nil))
+	if tab.SeqLen() != 1 {
+		t.Errorf("Expected setting 2 to nil to give length 1, got %d", tab.SeqLen())
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	cp := tab.DeepCopy()
+	if !cp.Equals(tab) {
+		t.Fatalf("Expected copy to equal the original")
+	}
+	_, volne, err := cp.GetStringPath("HarbingerTools_Events", "Characters", "Moon Guard", "Volne")
+	if err != nil {
+		t.Fatalf("Unexpected error getting path: %v", err)
+	}
+	volne.GetTable().Remove(1)
+	if cp.Equals(tab) {
+		t.Errorf("Expected modifying the copy to leave the original unchanged")
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tab := NewTable()
+	err := tab.SetStringPath(NewNode(NodeTypeString, "Hallow's End"), "Characters", "Moon Guard", "Volne", "title")
+	if err != nil {
+		t.Fatalf("Unexpected error setting path: %v", err)
+	}
+	_, n, err := tab.GetStringPath("Characters", "Moon Guard", "Volne", "title")
+	if err != nil || n.GetString() != "Hallow's End" {
+		t.Errorf("Expected to read back the value, got %v, %v", n, err)
+	}
+
+	err = tab.SetStringPath(NewNode(NodeTypeNumber, 1), "Characters", "Moon Guard", "Volne", "title", "day")
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) || !errors.Is(err, ErrNotTable) {
+		t.Fatalf("Expected a *PathError wrapping ErrNotTable, got %v", err)
+	}
+	expected_path := `Characters/"Moon Guard"/Volne/title`
+	if pathErr.Path.String() != expected_path {
+		t.Errorf("Expected conflict at %q, got %q", expected_path, pathErr.Path)
+	}
+
+	if err := tab.DeletePath(stringPath([]string{"Characters", "Moon Guard", "Volne"})...); err != nil {
+		t.Errorf("Unexpected error deleting path: %v", err)
+	}
+	if err := tab.DeletePath(stringPath([]string{"Characters", "Moon Guard", "Volne"})...); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting a missing path, got %v", err)
+	}
+}
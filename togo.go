@@ -0,0 +1,169 @@
+package wowlua
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// KeyMode selects the map type ToGo uses for tables.
+type KeyMode int
+
+const (
+	// KeysAuto uses map[string]interface{} for tables whose keys are all
+	// strings and map[interface{}]interface{} for others.
+	KeysAuto KeyMode = iota
+	// KeysInterface always uses map[interface{}]interface{}.
+	KeysInterface
+	// KeysString always uses map[string]interface{}. Other keys are
+	// converted to strings the way Lua's tostring does, so 1 becomes "1"
+	// and the entries for 1 and "1" would collide; the later one wins.
+	KeysString
+)
+
+// NumberMode selects the Go type ToGo uses for numbers.
+type NumberMode int
+
+const (
+	// NumbersAuto gives int64 for integers and float64 for floats.
+	NumbersAuto NumberMode = iota
+	// NumbersFloat64 gives float64 for every number, as encoding/json does.
+	NumbersFloat64
+	// NumbersAsNumber gives the Number itself, which keeps its lexeme.
+	// Numbers used as map keys drop the lexeme and floats with integer
+	// values become integers, as in Table keys, so m[IntNumber(16)] finds a
+	// key written 0x10.
+	NumbersAsNumber
+)
+
+// GoOptions controls how tables are converted to plain Go values. The zero
+// value is what Table.ToGo uses.
+type GoOptions struct {
+	// SequencesAsMaps converts sequences to maps like other tables instead
+	// of to []interface{}.
+	SequencesAsMaps bool
+	// Keys selects the map type for tables that aren't converted to slices.
+	Keys KeyMode
+	// Numbers selects the type for numbers.
+	Numbers NumberMode
+}
+
+// ToGo converts the table to plain Go values using the default GoOptions.
+// Tables whose keys are exactly 1 to n become []interface{}, tables with
+// only string keys map[string]interface{}, and other tables, including
+// mixed tables with a sequence part and other keys,
+// map[interface{}]interface{}. Strings become string, bools bool, integers
+// int64 and floats float64.
+func (t *Table) ToGo() interface{} {
+	return GoOptions{}.ToGo(t)
+}
+
+// ToGo converts the table to plain Go values. Tables used as keys stay
+// *Table in map[interface{}]interface{} keys. A table that appears more than
+// once in the tree, including one that contains itself, is converted once
+// and the result shared.
+func (o GoOptions) ToGo(t *Table) interface{} {
+	return o.value(NewNode(NodeTypeTable, t), make(map[*Table]interface{}))
+}
+
+// FromGo converts a plain Go value, or any value NewTableFrom accepts, to a
+// node. It's the reverse of ToGo, except that keys ToGo converted to strings
+// stay strings and a value that contains itself is an error wrapping
+// ErrCycle.
+func FromGo(v interface{}) (*Node, error) {
+	e := &marshaler{active: make(map[marshalRef]bool)}
+	return e.node(nil, reflect.ValueOf(v))
+}
+
+// value converts n. done holds the tables already converted.
+func (o GoOptions) value(n *Node, done map[*Table]interface{}) interface{} {
+	switch n.GetType() {
+	case NodeTypeString, NodeTypeIdentifier:
+		return n.GetString()
+	case NodeTypeBool:
+		return n.GetBool()
+	case NodeTypeNumber:
+		switch {
+		case o.Numbers == NumbersAsNumber:
+			return n.GetNumber()
+		case o.Numbers == NumbersAuto && n.IsInteger():
+			return n.GetInt64()
+		}
+		return n.GetFloat64()
+	case NodeTypeTable:
+		return o.table(n.GetTable(), done)
+	}
+	return nil
+}
+
+func (o GoOptions) table(t *Table, done map[*Table]interface{}) interface{} {
+	if v, ok := done[t]; ok {
+		return v
+	}
+	if seqLen := t.SeqLen(); !o.SequencesAsMaps && seqLen > 0 && seqLen == t.Len() {
+		s := make([]interface{}, seqLen)
+		done[t] = s
+		for i := range s {
+			s[i] = o.value(t.Get(intKey(i+1)), done)
+		}
+		return s
+	}
+
+	stringKeys := o.Keys == KeysString
+	if o.Keys == KeysAuto {
+		stringKeys = true
+		for _, e := range t.entries {
+			if e.key.GetType() != NodeTypeString {
+				stringKeys = false
+				break
+			}
+		}
+	}
+	if stringKeys {
+		m := make(map[string]interface{}, t.Len())
+		done[t] = m
+		for _, e := range t.entries {
+			m[keyString(e.key)] = o.value(e.value, done)
+		}
+		return m
+	}
+	m := make(map[interface{}]interface{}, t.Len())
+	done[t] = m
+	for _, e := range t.entries {
+		var k interface{} = e.key.GetTable()
+		switch {
+		case e.key.GetType() == NodeTypeNumber && o.Numbers == NumbersAsNumber:
+			k = keyNumber(e.key.GetNumber())
+		case e.key.GetType() != NodeTypeTable:
+			k = o.value(e.key, done)
+		}
+		m[k] = o.value(e.value, done)
+	}
+	return m
+}
+
+// keyNumber returns n as a map key that compares by value.
+func keyNumber(n Number) Number {
+	if i, ok := n.Int64(); ok {
+		return IntNumber(i)
+	}
+	return FloatNumber(n.Float64())
+}
+
+// keyString converts a key to a string as Lua's tostring does.
+func keyString(k *Node) string {
+	switch k.GetType() {
+	case NodeTypeString, NodeTypeIdentifier:
+		return k.GetString()
+	case NodeTypeNumber:
+		n := k.GetNumber()
+		if i, ok := n.Int64(); ok && n.IsInteger() {
+			return strconv.FormatInt(i, 10)
+		}
+		s, _ := formatNumber(n, false)
+		return s
+	case NodeTypeBool:
+		return strconv.FormatBool(k.GetBool())
+	}
+	return fmt.Sprintf("table: %p", k.GetTable())
+}
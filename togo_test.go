@@ -0,0 +1,79 @@
+package wowlua
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToGo(t *testing.T) {
+	tab := Map(
+		"seq", Seq("a", 2, 2.5),
+		"mixed", Map(1, "one", "name", "x"),
+		"flags", Map(true, "yes"),
+	)
+	tests := []struct {
+		name     string
+		opts     GoOptions
+		expected interface{}
+	}{
+		{"default", GoOptions{}, map[string]interface{}{
+			"seq":   []interface{}{"a", int64(2), 2.5},
+			"mixed": map[interface{}]interface{}{int64(1): "one", "name": "x"},
+			"flags": map[interface{}]interface{}{true: "yes"},
+		}},
+		{"string keys and floats", GoOptions{SequencesAsMaps: true, Keys: KeysString, Numbers: NumbersFloat64}, map[string]interface{}{
+			"seq":   map[string]interface{}{"1": "a", "2": 2.0, "3": 2.5},
+			"mixed": map[string]interface{}{"1": "one", "name": "x"},
+			"flags": map[string]interface{}{"true": "yes"},
+		}},
+		{"interface keys and numbers", GoOptions{Keys: KeysInterface, Numbers: NumbersAsNumber}, map[interface{}]interface{}{
+			"seq":   []interface{}{"a", IntNumber(2), FloatNumber(2.5)},
+			"mixed": map[interface{}]interface{}{IntNumber(1): "one", "name": "x"},
+			"flags": map[interface{}]interface{}{true: "yes"},
+		}},
+	}
+	for _, test := range tests {
+		v := test.opts.ToGo(tab)
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("Expected %s conversion %#v, got %#v", test.name, test.expected, v)
+		}
+	}
+}
+
+func TestToGoNumberKeys(t *testing.T) {
+	tab, err := ParseLua("T = { [0x10] = 'hex', [2.0] = 'float', [1.5] = 'half', ['x'] = 1 }")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m := GoOptions{Numbers: NumbersAsNumber}.ToGo(tab.GetByString("T").GetTable()).(map[interface{}]interface{})
+	if m[IntNumber(16)] != "hex" || m[IntNumber(2)] != "float" || m[FloatNumber(1.5)] != "half" {
+		t.Errorf("Expected number keys to be found by value, got %#v", m)
+	}
+	if n, ok := m["x"].(Number); !ok || n.Lexeme() != "1" {
+		t.Errorf("Expected values to keep their lexemes, got %#v", m["x"])
+	}
+}
+
+func TestToGoCycle(t *testing.T) {
+	tab := Map("name", "loop")
+	tab.Set(NewNode(NodeTypeString, "self"), NewNode(NodeTypeTable, tab))
+	m, ok := tab.ToGo().(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected a map, got %#v", tab.ToGo())
+	}
+	self, ok := m["self"].(map[string]interface{})
+	if !ok || reflect.ValueOf(self).Pointer() != reflect.ValueOf(m).Pointer() {
+		t.Errorf("Expected self to be the same map")
+	}
+}
+
+func TestFromGo(t *testing.T) {
+	tab := Map("seq", Seq("a", 2, 2.5), "mixed", Map(1, "one", "name", "x"), "flags", Map(true, "yes"))
+	n, err := FromGo(tab.ToGo())
+	if err != nil {
+		t.Fatalf("Unexpected error converting back: %v", err)
+	}
+	if !n.GetTable().Equals(tab) {
+		t.Errorf("Expected FromGo(ToGo()) to give the original table, got %v", n)
+	}
+}
@@ -1,22 +1,11 @@
 package wowlua
 
 import (
-	"bufio"
-	"errors"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
-	"unicode"
-)
-
-const (
-	StateTokenNone = iota
-	StateTokenBareHyphen
-	StateTokenFindNewline
-	StateTokenString
-	StateTokenEscapedChar
-	StateTokenNumber
-	StateTokenIdentifier
-	StateTokenInvalid
+	"unicode/utf8"
 )
 
 const (
@@ -32,6 +21,21 @@ const (
 	TokenTypeIdentifier
 )
 
+// States of the original rune-at-a-time tokenizer.
+//
+// Deprecated: the tokenizer no longer has these states. They're kept so code
+// using SetStateToken still compiles.
+const (
+	StateTokenNone = iota
+	StateTokenBareHyphen
+	StateTokenFindNewline
+	StateTokenString
+	StateTokenEscapedChar
+	StateTokenNumber
+	StateTokenIdentifier
+	StateTokenInvalid
+)
+
 var (
 	tokenTypeStrings = map[int]string{
 		TokenTypeStartTable: "Start Table",
@@ -45,18 +49,20 @@ var (
 		TokenTypeNumber:     "Number",
 		TokenTypeIdentifier: "Identifier",
 	}
-	tokenStartTable = &Token{Type: TokenTypeStartTable}
-	tokenEndTable   = &Token{Type: TokenTypeEndTable}
-	tokenStartKey   = &Token{Type: TokenTypeStartKey}
-	tokenEndKey     = &Token{Type: TokenTypeEndKey}
-	tokenEquals     = &Token{Type: TokenTypeEquals}
-	tokenComma      = &Token{Type: TokenTypeComma}
+	tokenStartKey = &Token{Type: TokenTypeStartKey}
+	tokenEndKey   = &Token{Type: TokenTypeEndKey}
 )
 
-// A Token is a symbol identified by the tokenizer.
+// A Token is a symbol identified by the tokenizer. Number tokens produced by
+// the tokenizer carry their parsed value in Number. Offset and End give the
+// token's position in the input as byte offsets; input[Offset:End] is the
+// token's exact text.
 type Token struct {
-	Type  int
-	Value string
+	Type   int
+	Value  string
+	Number Number
+	Offset int
+	End    int
 }
 
 // Create a new token.
@@ -79,13 +85,31 @@ func (t Token) String() string {
 	return s
 }
 
-// A Tokenizer processes a string, yielding Tokens.
+// A SyntaxError describes malformed input and where it was found.
+type SyntaxError struct {
+	Msg    string
+	Offset int // Byte offset of the error in the input
+	Line   int // 1-based line number
+	Column int // 1-based column, counted in bytes
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// A Tokenizer processes a string, yielding Tokens. It scans the input bytes
+// directly and token values are substrings of the input where possible, so
+// tokenizing allocates little beyond the tokens' values themselves. Note that
+// this means values may keep the input string from being garbage collected.
 type Tokenizer struct {
-	buffer   []rune
-	state    int
-	scanner  *bufio.Scanner
+	src      string
+	pos      int
 	callback func(*Token) error
-	err      error
+	err      error // From a callback called through Emit
+
+	// For the deprecated Buffer, Send and SetStateToken
+	buffer []rune
+	state  int
 }
 
 // NewTokenizer creates a new Tokenizer to process the supplied string. It will
@@ -96,29 +120,69 @@ func NewTokenizer(s string, c func(*Token) error) *Tokenizer {
 		c = func(tok *Token) error { fmt.Println(*tok); return nil }
 	}
 	t := &Tokenizer{
-		buffer:   []rune{},
-		state:    StateTokenNone,
-		scanner:  bufio.NewScanner(strings.NewReader(s)),
+		src:      s,
 		callback: c,
 	}
-	t.scanner.Split(bufio.ScanRunes)
 	return t
 }
 
+// NewTokenizerBytes is like NewTokenizer but processes a byte slice. The
+// bytes are copied once so token values don't alias b.
+func NewTokenizerBytes(b []byte, c func(*Token) error) *Tokenizer {
+	return NewTokenizer(string(b), c)
+}
+
+// Process in the input stream until it's finished or an error is encountered.
+func (t *Tokenizer) Tokenize() error {
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		t.Emit(&tok)
+		if t.err != nil {
+			return t.err
+		}
+	}
+}
+
 // Add a rune to the buffer.
+//
+// Deprecated: the tokenizer no longer buffers runes. Buffer is kept for use
+// with Send.
 func (t *Tokenizer) Buffer(r rune) {
 	t.buffer = append(t.buffer, r)
 }
 
 // Create a new token from the buffer of the specified type, Emit() the token,
 // then clear the buffer.
+//
+// Deprecated: the tokenizer no longer buffers runes.
 func (t *Tokenizer) Send(pType int) {
 	t.Emit(NewToken(pType, string(t.buffer)))
 	t.buffer = t.buffer[:0]
 }
 
+// Create a number token from the buffer, Emit() the token, then clear the
+// buffer.
+//
+// Deprecated: the tokenizer no longer buffers runes.
+func (t *Tokenizer) SendNumber() error {
+	lexeme := string(t.buffer)
+	t.buffer = t.buffer[:0]
+	n, err := ParseNumber(lexeme)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, lexeme)
+	}
+	t.Emit(&Token{Type: TokenTypeNumber, Value: lexeme, Number: n})
+	return nil
+}
+
 // If there's been no previous error, send a token to the callback and capture
-// any returned error.
+// any returned error, which stops Tokenize.
 func (t *Tokenizer) Emit(tok *Token) {
 	if t.err != nil {
 		return
@@ -127,6 +191,9 @@ func (t *Tokenizer) Emit(tok *Token) {
 }
 
 // Set the current state. If the specified state is invalid, nothing happens.
+//
+// Deprecated: the tokenizer no longer has states, so this has no effect on
+// tokenizing.
 func (t *Tokenizer) SetStateToken(state int) {
 	if state < StateTokenNone || state >= StateTokenInvalid {
 		return
@@ -134,104 +201,271 @@ func (t *Tokenizer) SetStateToken(state int) {
 	t.state = state
 }
 
-// Process in the input stream until it's finished or an error is encountered.
-func (t *Tokenizer) Tokenize() error {
-	for t.scanner.Scan() {
-		if t.err != nil {
-			return t.err
-		}
-		r := rune(t.scanner.Text()[0])
-		switch t.state {
-		case StateTokenNone:
-			switch {
-			case r == '{':
-				t.Emit(tokenStartTable)
-			case r == '}':
-				t.Emit(tokenEndTable)
-			case r == '[':
-				t.Emit(tokenStartKey)
-			case r == ']':
-				t.Emit(tokenEndKey)
-			case r == '-':
-				t.Buffer(r)
-				t.SetStateToken(StateTokenBareHyphen)
-			case r == '=':
-				t.Emit(tokenEquals)
-			case r == '"':
-				t.SetStateToken(StateTokenString)
-			case r == ',':
-				t.Emit(tokenComma)
-			case unicode.IsDigit(r):
-				t.Buffer(r)
-				t.SetStateToken(StateTokenNumber)
-			case unicode.IsSpace(r):
-				/* Do Nothing */
-			case unicode.IsLetter(r):
-				t.Buffer(r)
-				t.SetStateToken(StateTokenIdentifier)
-			default:
-				return errors.New("FECK None")
+// Next scans and returns the next token. At the end of the input it returns
+// io.EOF. Errors in the input are returned as *SyntaxError.
+func (t *Tokenizer) Next() (Token, error) {
+	src := t.src
+	for t.pos < len(src) {
+		start := t.pos
+		c := src[start]
+		switch c {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+			t.pos++
+			continue
+		case '{':
+			return t.punct(TokenTypeStartTable), nil
+		case '}':
+			return t.punct(TokenTypeEndTable), nil
+		case ']':
+			return t.punct(TokenTypeEndKey), nil
+		case '=':
+			return t.punct(TokenTypeEquals), nil
+		case ',', ';':
+			return t.punct(TokenTypeComma), nil
+		case '[':
+			if level := longBracketLevel(src[start:]); level >= 0 {
+				return t.scanLongString(level)
 			}
-		case StateTokenBareHyphen:
-			switch {
-			case r == '-':
-				t.Send(TokenTypeIgnore)
-				t.SetStateToken(StateTokenFindNewline)
-			case unicode.IsDigit(r):
-				t.Buffer(r)
-				t.SetStateToken(StateTokenNumber)
-			default:
-				return errors.New("FECK")
+			return t.punct(TokenTypeStartKey), nil
+		case '"', '\'':
+			return t.scanString(c)
+		case '-':
+			if strings.HasPrefix(src[start:], "--") {
+				return t.scanComment()
 			}
-		case StateTokenFindNewline:
-			if r == '\n' {
-				t.SetStateToken(StateTokenNone)
+			if start+1 < len(src) && (isDigit(src[start+1]) || src[start+1] == '.') {
+				return t.scanNumber()
 			}
-		case StateTokenString:
-			switch r {
-			case '\\':
-				t.SetStateToken(StateTokenEscapedChar)
-			case '"':
-				t.Send(TokenTypeString)
-				t.SetStateToken(StateTokenNone)
-			default:
-				t.Buffer(r)
+			return Token{}, t.errorf(start, "unexpected '-'")
+		}
+		switch {
+		case isDigit(c) || c == '.':
+			return t.scanNumber()
+		case isNameStart(c):
+			for t.pos++; t.pos < len(src) && isNameByte(src[t.pos]); t.pos++ {
+			}
+			return Token{Type: TokenTypeIdentifier, Value: src[start:t.pos], Offset: start, End: t.pos}, nil
+		}
+		r, _ := utf8.DecodeRuneInString(src[start:])
+		return Token{}, t.errorf(start, "unexpected character %q", r)
+	}
+	return Token{}, io.EOF
+}
+
+func (t *Tokenizer) punct(tType int) Token {
+	t.pos++
+	return Token{Type: tType, Offset: t.pos - 1, End: t.pos}
+}
+
+func (t *Tokenizer) errorf(offset int, tmpl string, v ...interface{}) *SyntaxError {
+	prefix := t.src[:offset]
+	line := strings.Count(prefix, "\n") + 1
+	column := offset - strings.LastIndexByte(prefix, '\n')
+	return &SyntaxError{Msg: fmt.Sprintf(tmpl, v...), Offset: offset, Line: line, Column: column}
+}
+
+func (t *Tokenizer) scanNumber() (Token, error) {
+	src := t.src
+	start := t.pos
+	t.pos++ // A sign, digit or '.'
+	for t.pos < len(src) && isNumberByte(src[t.pos-1], src[t.pos]) {
+		t.pos++
+	}
+	lexeme := src[start:t.pos]
+	n, err := ParseNumber(lexeme)
+	if err != nil {
+		return Token{}, t.errorf(start, "%v: %q", err, lexeme)
+	}
+	return Token{Type: TokenTypeNumber, Value: lexeme, Number: n, Offset: start, End: t.pos}, nil
+}
 
+// scanComment scans a line comment or long comment. The token's value is the
+// comment's full text.
+func (t *Tokenizer) scanComment() (Token, error) {
+	src := t.src
+	start := t.pos
+	t.pos += 2
+	if level := longBracketLevel(src[t.pos:]); level >= 0 {
+		closer := "]" + strings.Repeat("=", level) + "]"
+		end := strings.Index(src[t.pos:], closer)
+		if end < 0 {
+			return Token{}, t.errorf(start, "unterminated long comment")
+		}
+		t.pos += end + len(closer)
+	} else if end := strings.IndexByte(src[t.pos:], '\n'); end >= 0 {
+		t.pos += end
+	} else {
+		t.pos = len(src)
+	}
+	return Token{Type: TokenTypeIgnore, Value: src[start:t.pos], Offset: start, End: t.pos}, nil
+}
+
+// longBracketLevel returns the level of the long bracket at the start of s,
+// the number of '=' between its brackets, or -1 if s doesn't start with one.
+func longBracketLevel(s string) int {
+	if len(s) < 2 || s[0] != '[' {
+		return -1
+	}
+	level := 0
+	for level+1 < len(s) && s[level+1] == '=' {
+		level++
+	}
+	if level+1 < len(s) && s[level+1] == '[' {
+		return level
+	}
+	return -1
+}
+
+func (t *Tokenizer) scanLongString(level int) (Token, error) {
+	src := t.src
+	start := t.pos
+	t.pos += level + 2
+	// A newline immediately after the opening bracket isn't part of the string
+	if strings.HasPrefix(src[t.pos:], "\r\n") {
+		t.pos += 2
+	} else if t.pos < len(src) && (src[t.pos] == '\n' || src[t.pos] == '\r') {
+		t.pos++
+	}
+	closer := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(src[t.pos:], closer)
+	if end < 0 {
+		return Token{}, t.errorf(start, "unterminated long string")
+	}
+	value := src[t.pos : t.pos+end]
+	t.pos += end + len(closer)
+	return Token{Type: TokenTypeString, Value: value, Offset: start, End: t.pos}, nil
+}
+
+func (t *Tokenizer) scanString(quote byte) (Token, error) {
+	src := t.src
+	start := t.pos
+	escaped := false
+	for t.pos++; t.pos < len(src); t.pos++ {
+		switch src[t.pos] {
+		case '\\':
+			escaped = true
+			t.pos++
+		case '\n':
+			return Token{}, t.errorf(start, "unterminated string")
+		case quote:
+			t.pos++
+			value := src[start+1 : t.pos-1]
+			if escaped {
+				var err error
+				if value, err = unescapeLua(value); err != nil {
+					return Token{}, t.errorf(start, "%v", err)
+				}
+			}
+			return Token{Type: TokenTypeString, Value: value, Offset: start, End: t.pos}, nil
+		}
+	}
+	return Token{}, t.errorf(start, "unterminated string")
+}
+
+// unescapeLua interprets the escape sequences in the body of a quoted Lua
+// string.
+func unescapeLua(s string) (string, error) {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b = append(b, c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("incomplete escape sequence")
+		}
+		switch c = s[i]; c {
+		case 'a':
+			b = append(b, '\a')
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n', '\n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'v':
+			b = append(b, '\v')
+		case '\\', '"', '\'':
+			b = append(b, c)
+		case '\r':
+			b = append(b, '\n')
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case 'x':
+			if i+2 >= len(s) {
+				return "", fmt.Errorf("incomplete \\x escape")
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\x escape %q", s[i-1:i+3])
+			}
+			b = append(b, byte(v))
+			i += 2
+		case 'z':
+			for i+1 < len(s) && strings.IndexByte(" \t\n\r\v\f", s[i+1]) >= 0 {
+				i++
+			}
+		case 'u':
+			end := strings.IndexByte(s[i:], '}')
+			if !strings.HasPrefix(s[i+1:], "{") || end < 0 {
+				return "", fmt.Errorf("invalid \\u escape")
 			}
-		case StateTokenEscapedChar:
-			t.Buffer(r)
-			t.SetStateToken(StateTokenString)
-		case StateTokenNumber:
-			switch {
-			case r == ',':
-				t.Send(TokenTypeNumber)
-				t.SetStateToken(StateTokenNone)
-				t.Emit(tokenComma)
-			case unicode.IsDigit(r):
-				t.Buffer(r)
-			case r == '.':
-				t.Buffer(r)
-			case r == ']':
-				t.Send(TokenTypeNumber)
-				t.SetStateToken(StateTokenNone)
-				t.Emit(tokenEndKey)
-			default:
-				t.Send(TokenTypeNumber)
-				t.SetStateToken(StateTokenNone)
+			v, err := strconv.ParseUint(s[i+2:i+end], 16, 31)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\u escape %q", s[i-1:i+end+1])
 			}
-		case StateTokenIdentifier:
-			switch {
-			case r == ',':
-				t.Send(TokenTypeIdentifier)
-				t.SetStateToken(StateTokenNone)
-				t.Emit(tokenComma)
-			case unicode.IsSpace(r):
-				t.Send(TokenTypeIdentifier)
-				t.SetStateToken(StateTokenNone)
-			default:
-				t.Buffer(r)
+			b = append(b, string(rune(v))...)
+			i += end
+		default:
+			if !isDigit(c) {
+				return "", fmt.Errorf("invalid escape sequence \\%c", c)
 			}
+			v, n := 0, 0
+			for ; n < 3 && i+n < len(s) && isDigit(s[i+n]); n++ {
+				v = v*10 + int(s[i+n]-'0')
+			}
+			if v > 255 {
+				return "", fmt.Errorf("decimal escape too large")
+			}
+			b = append(b, byte(v))
+			i += n - 1
 		}
 	}
-	return nil
+	return string(b), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isNumberByte returns whether c continues a number whose previous byte was
+// prev. Anything that could be part of a decimal or hexadecimal number is
+// accepted; ParseNumber rejects malformed combinations.
+func isNumberByte(prev, c byte) bool {
+	switch {
+	case isDigit(c), 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		return true
+	case c == '.', c == 'x', c == 'X', c == 'p', c == 'P':
+		return true
+	case c == '-', c == '+':
+		return prev == 'e' || prev == 'E' || prev == 'p' || prev == 'P'
+	}
+	return false
+}
+
+// isNameStart returns whether c can start a name. Bytes of multi-byte UTF-8
+// sequences are accepted so names in other scripts don't stop the tokenizer.
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= utf8.RuneSelf
+}
+
+func isNameByte(c byte) bool {
+	return isNameStart(c) || isDigit(c)
 }
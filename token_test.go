@@ -0,0 +1,107 @@
+package wowlua
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenizerValues(t *testing.T) {
+	input := `-- comment
+X = { "a\"b\\c\n", 'single', [[long
+string]], [==[with ]] inside]==], "\65\x42\u{43}", --[[ long
+comment ]] -1.5e-3, 0x1F, name_1 }`
+	var tokens []Token
+	err := NewTokenizer(input, func(tok *Token) error {
+		tokens = append(tokens, *tok)
+		return nil
+	}).Tokenize()
+	if err != nil {
+		t.Fatalf("Unexpected error tokenizing: %v", err)
+	}
+	expected := []struct {
+		tType int
+		value string
+	}{
+		{TokenTypeIgnore, "-- comment"},
+		{TokenTypeIdentifier, "X"},
+		{TokenTypeEquals, ""},
+		{TokenTypeStartTable, ""},
+		{TokenTypeString, "a\"b\\c\n"},
+		{TokenTypeComma, ""},
+		{TokenTypeString, "single"},
+		{TokenTypeComma, ""},
+		{TokenTypeString, "long\nstring"},
+		{TokenTypeComma, ""},
+		{TokenTypeString, "with ]] inside"},
+		{TokenTypeComma, ""},
+		{TokenTypeString, "ABC"},
+		{TokenTypeComma, ""},
+		{TokenTypeIgnore, "--[[ long\ncomment ]]"},
+		{TokenTypeNumber, "-1.5e-3"},
+		{TokenTypeComma, ""},
+		{TokenTypeNumber, "0x1F"},
+		{TokenTypeComma, ""},
+		{TokenTypeIdentifier, "name_1"},
+		{TokenTypeEndTable, ""},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %v tokens, got %v: %v", len(expected), len(tokens), tokens)
+	}
+	for i, e := range expected {
+		if tokens[i].Type != e.tType || tokens[i].Value != e.value {
+			t.Errorf("Expected token %v to be %v %q, got %v", i, tokenTypeStrings[e.tType], e.value, tokens[i])
+		}
+		if tokens[i].Type != TokenTypeString && tokens[i].Type != TokenTypeIgnore && tokens[i].Value != "" &&
+			input[tokens[i].Offset:tokens[i].End] != tokens[i].Value {
+			t.Errorf("Expected token %v to span %q, got %q", i, tokens[i].Value, input[tokens[i].Offset:tokens[i].End])
+		}
+	}
+	if !tokens[15].Number.Equals(FloatNumber(-0.0015)) || tokens[17].Number.Float64() != 31 {
+		t.Errorf("Expected parsed numbers, got %v and %v", tokens[15].Number, tokens[17].Number)
+	}
+}
+
+func TestTokenizerErrorPosition(t *testing.T) {
+	err := NewTokenizer("X = {\n\t1,\n\t\"unterminated\n}", func(*Token) error { return nil }).Tokenize()
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected a *SyntaxError, got %v", err)
+	}
+	if syntaxErr.Line != 3 || syntaxErr.Column != 2 {
+		t.Errorf("Expected error at line 3, column 2, got %v", syntaxErr)
+	}
+}
+
+func TestTokenizerCallbackTokens(t *testing.T) {
+	var tokens []*Token
+	err := NewTokenizer(`X = 1`, func(tok *Token) error {
+		tokens = append(tokens, tok)
+		return nil
+	}).Tokenize()
+	if err != nil {
+		t.Fatalf("Unexpected error tokenizing: %v", err)
+	}
+	if len(tokens) != 3 || tokens[0].Value != "X" || tokens[1].Type != TokenTypeEquals || tokens[2].Value != "1" {
+		t.Errorf("Expected kept tokens to be unchanged, got %v", tokens)
+	}
+}
+
+func TestTokenizerDeprecated(t *testing.T) {
+	var got []Token
+	tz := NewTokenizer("", func(tok *Token) error {
+		got = append(got, *tok)
+		return nil
+	})
+	for _, r := range "name" {
+		tz.Buffer(r)
+	}
+	tz.Send(TokenTypeIdentifier)
+	tz.Buffer('4')
+	tz.Buffer('2')
+	if err := tz.SendNumber(); err != nil {
+		t.Fatalf("Unexpected error sending number: %v", err)
+	}
+	if len(got) != 2 || got[0].Value != "name" || got[1].Number.Float64() != 42 {
+		t.Errorf("Expected name and 42, got %v", got)
+	}
+}
@@ -0,0 +1,87 @@
+package wowlua
+
+import (
+	"strconv"
+)
+
+// NodeType identifies the kind of value a Node or Value holds.
+type NodeType int
+
+const (
+	// NodeTypeString is a node containing a string
+	NodeTypeString NodeType = iota
+	// NodeTypeIdentifier is a node containing an identifier. The parser never
+	// produces these; bare names are parsed as strings.
+	NodeTypeIdentifier
+	// NodeTypeNumber is a node containing a number
+	NodeTypeNumber
+	// NodeTypeBool is a node containing a bool
+	NodeTypeBool
+	// NodeTypeTable is a node containing a table
+	NodeTypeTable
+	// NodeTypeNil is a node containing nil
+	NodeTypeNil
+
+	// nodeTypeTableEntry is used by the parser for entries under construction
+	nodeTypeTableEntry
+)
+
+var nodeTypeStrings = map[NodeType]string{
+	NodeTypeString:     "string",
+	NodeTypeIdentifier: "identifier",
+	NodeTypeNumber:     "number",
+	NodeTypeBool:       "boolean",
+	NodeTypeTable:      "table",
+	NodeTypeNil:        "nil",
+	nodeTypeTableEntry: "table entry",
+}
+
+// String returns the Lua name of the type.
+func (t NodeType) String() string {
+	if s, ok := nodeTypeStrings[t]; ok {
+		return s
+	}
+	return "NodeType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// A Value is a Lua value: a String, Number, Bool, Nil or *Table. Use a type
+// switch to tell them apart.
+type Value interface {
+	// Type returns the type of the value
+	Type() NodeType
+	isValue()
+}
+
+// String is a Lua string value
+type String string
+
+// Bool is a Lua boolean value
+type Bool bool
+
+// Nil is the Lua nil value
+type Nil struct{}
+
+// Type returns NodeTypeString
+func (String) Type() NodeType { return NodeTypeString }
+
+// Type returns NodeTypeNumber
+func (Number) Type() NodeType { return NodeTypeNumber }
+
+// Type returns NodeTypeBool
+func (Bool) Type() NodeType { return NodeTypeBool }
+
+// Type returns NodeTypeNil
+func (Nil) Type() NodeType { return NodeTypeNil }
+
+// Type returns NodeTypeTable
+func (*Table) Type() NodeType { return NodeTypeTable }
+
+func (String) isValue()      {}
+func (Number) isValue()      {}
+func (Bool) isValue()        {}
+func (Nil) isValue()         {}
+func (*Table) isValue()      {}
+func (*tableEntry) isValue() {}
+
+// Type returns the parser-internal table entry type
+func (*tableEntry) Type() NodeType { return nodeTypeTableEntry }
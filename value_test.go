@@ -0,0 +1,45 @@
+package wowlua
+
+import (
+	"testing"
+)
+
+func TestValueTypeSwitch(t *testing.T) {
+	tab, err := ParseLua(`Values = { "a", 2, true, nil, {} }`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	values := tab.GetByString("Values").GetTable()
+	if values.Len() != 4 || values.HasKey(NodeOf(IntNumber(4))) {
+		t.Fatalf("Expected nil to leave a hole at 4, got %v", values.Keys())
+	}
+	expected_types := map[int64]NodeType{
+		1: NodeTypeString,
+		2: NodeTypeNumber,
+		3: NodeTypeBool,
+		5: NodeTypeTable,
+	}
+	for _, k := range values.Keys() {
+		i := k.GetInt64()
+		var got NodeType
+		switch v := values.Get(k).Value().(type) {
+		case String:
+			got = v.Type()
+		case Number:
+			got = v.Type()
+		case Bool:
+			got = v.Type()
+		case *Table:
+			got = v.Type()
+		default:
+			t.Errorf("Unexpected value type %T at %v", v, i)
+			continue
+		}
+		if got != expected_types[i] {
+			t.Errorf("Expected %v at %v, got %v", expected_types[i], i, got)
+		}
+	}
+	if NodeTypeBool.String() != "boolean" {
+		t.Errorf("Expected NodeTypeBool to be %q, got %q", "boolean", NodeTypeBool)
+	}
+}
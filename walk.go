@@ -0,0 +1,102 @@
+package wowlua
+
+// WalkAction tells a walk how to proceed after visiting a node.
+type WalkAction int
+
+const (
+	// WalkContinue continues the walk normally
+	WalkContinue WalkAction = iota
+	// WalkSkip continues the walk without visiting the current node's
+	// children. In a post-order walk the children have already been visited
+	// so this is the same as WalkContinue.
+	WalkSkip
+	// WalkStop ends the walk
+	WalkStop
+)
+
+// WalkFunc is called for each node visited by a walk. The root is visited
+// with an empty path and a nil key.
+type WalkFunc func(path Path, key, value *Node) WalkAction
+
+// Walk visits the node and, if it is a table, everything nested within it.
+// Each node is visited before its children and entries are visited in table
+// order. Walk returns WalkStop if fn stopped the walk early.
+func Walk(node *Node, fn WalkFunc) WalkAction {
+	return walk(nil, nil, node, fn, false)
+}
+
+// WalkPostOrder is like Walk, but each node is visited after its children.
+func WalkPostOrder(node *Node, fn WalkFunc) WalkAction {
+	return walk(nil, nil, node, fn, true)
+}
+
+// Walk visits every entry of the table and everything nested within them. The
+// table itself isn't visited.
+func (t *Table) Walk(fn WalkFunc) WalkAction {
+	return walkEntries(nil, t, fn, false)
+}
+
+func walk(path Path, key, value *Node, fn WalkFunc, post bool) WalkAction {
+	if !post {
+		switch fn(path, key, value) {
+		case WalkStop:
+			return WalkStop
+		case WalkSkip:
+			return WalkContinue
+		}
+	}
+	// A nil node is visited like Nil
+	if value != nil {
+		if t := value.GetTable(); t != nil && walkEntries(path, t, fn, post) == WalkStop {
+			return WalkStop
+		}
+	}
+	if post && fn(path, key, value) == WalkStop {
+		return WalkStop
+	}
+	return WalkContinue
+}
+
+func walkEntries(path Path, t *Table, fn WalkFunc, post bool) WalkAction {
+	for _, e := range t.entries {
+		if walk(path.child(e.key), e.key, e.value, fn, post) == WalkStop {
+			return WalkStop
+		}
+	}
+	return WalkContinue
+}
+
+// TransformFunc is called for each node visited by Transform. It returns the
+// node to store in place of value, which may be value itself to leave it
+// unchanged or nil to delete the entry, and how to proceed.
+type TransformFunc func(path Path, key, value *Node) (*Node, WalkAction)
+
+// Transform walks the tree like Walk, replacing or deleting nodes as directed
+// by fn. Children of a replaced node are walked in the replacement. Deleting
+// entries from a sequence leaves holes; use Table.Remove to renumber. The
+// returned node is the replacement for the root, or nil if fn deleted it.
+func Transform(node *Node, fn TransformFunc) *Node {
+	n, _ := transform(nil, nil, node, fn)
+	return n
+}
+
+func transform(path Path, key, value *Node, fn TransformFunc) (*Node, WalkAction) {
+	n, action := fn(path, key, value)
+	if n == nil || action != WalkContinue {
+		return n, action
+	}
+	if t := n.GetTable(); t != nil {
+		for _, k := range t.Keys() {
+			child, childAction := transform(path.child(k), k, t.Get(k), fn)
+			if child == nil {
+				t.Delete(k)
+			} else {
+				t.Set(k, child)
+			}
+			if childAction == WalkStop {
+				return n, WalkStop
+			}
+		}
+	}
+	return n, WalkContinue
+}
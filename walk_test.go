@@ -0,0 +1,110 @@
+package wowlua
+
+import (
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	root := NewNode(NodeTypeTable, tab)
+
+	titles := 0
+	Walk(root, func(path Path, key, value *Node) WalkAction {
+		if path.String() == "HarbingerTools_GuildLog" {
+			return WalkSkip
+		}
+		if key != nil && key.GetString() == "title" {
+			titles++
+		}
+		return WalkContinue
+	})
+	if titles != 8 {
+		t.Errorf("Expected 8 titles, got %v", titles)
+	}
+
+	var found Path
+	Walk(root, func(path Path, key, value *Node) WalkAction {
+		if value.GetString() == "Ghank's Madness" || value.GetString() == "2v2 Wargames!" {
+			found = path
+			return WalkStop
+		}
+		return WalkContinue
+	})
+	expected_path := `HarbingerTools_Events/Guilds/"Moon Guard"/"Harbingers of Discord"[2]/title`
+	if found.String() != expected_path {
+		t.Errorf("Expected path %q, got %q", expected_path, found)
+	}
+
+	var order []string
+	WalkPostOrder(NewNode(NodeTypeTable, tab.GetByString("HarbingerTools_GuildLog").GetTable()),
+		func(path Path, key, value *Node) WalkAction {
+			if len(path) <= 1 {
+				order = append(order, path.String())
+			}
+			return WalkContinue
+		})
+	if len(order) != 2 || order[0] != `"Moon Guard"` || order[1] != "" {
+		t.Errorf("Expected children before parents, got %q", order)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tab, err := ParseLua(sample_data)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing data: %q", err)
+	}
+	Transform(NewNode(NodeTypeTable, tab), func(path Path, key, value *Node) (*Node, WalkAction) {
+		if key == nil {
+			return value, WalkContinue
+		}
+		switch key.GetString() {
+		case "nowYear", "nowMonth":
+			return nil, WalkContinue
+		case "title":
+			return NewNode(NodeTypeString, "redacted"), WalkContinue
+		}
+		return value, WalkContinue
+	})
+
+	_, event, err := tab.GetPath(
+		NewNode(NodeTypeString, "HarbingerTools_Events"),
+		NewNode(NodeTypeString, "Characters"),
+		NewNode(NodeTypeString, "Moon Guard"),
+		NewNode(NodeTypeString, "Volne"),
+		NewNode(NodeTypeNumber, 1),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error getting path: %v", err)
+	}
+	if event.GetTable().HasKeyByString("nowYear") {
+		t.Errorf("Expected nowYear to be deleted")
+	}
+	if title, _ := event.GetTable().GetStringByString("title"); title != "redacted" {
+		t.Errorf("Expected title to be replaced, got %q", title)
+	}
+}
+
+func TestWalkNil(t *testing.T) {
+	visits := 0
+	fn := func(path Path, key, value *Node) WalkAction {
+		visits++
+		return WalkContinue
+	}
+	Walk(nil, fn)
+	WalkPostOrder(nil, fn)
+	if visits != 2 {
+		t.Errorf("Expected the nil root to be visited once per walk, got %d visits", visits)
+	}
+	n := Transform(NodeOf(Map("a", 1)), func(path Path, key, value *Node) (*Node, WalkAction) {
+		return nil, WalkContinue
+	})
+	if n != nil {
+		t.Errorf("Expected Transform to delete the root, got %v", n)
+	}
+	if n := Transform(nil, func(path Path, key, value *Node) (*Node, WalkAction) { return value, WalkContinue }); n != nil {
+		t.Errorf("Expected Transform of nil to give nil, got %v", n)
+	}
+}